@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/chromedp/chromedp"
+
+	tasks "github.com/KatsutoshiOtogawa/dlsite_scraping_go"
+)
+
+func init() {
+	commands["save-keystore"] = command{
+		usage:   "save-keystore <path> LOGIN_USERNAME, LOGIN_PASSWORDをLOGIN_KEYSTORE_SECRETで暗号化して保存する",
+		offline: true,
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("保存先を指定してください。")
+			}
+			return chromedp.Tasks{
+				chromedp.ActionFunc(func(ctx context.Context) error {
+					c, err := tasks.EnvCredentials{}.Credential(ctx)
+					if err != nil {
+						return err
+					}
+					if err := tasks.SaveKeystore(args[0], os.Getenv("LOGIN_KEYSTORE_SECRET"), c); err != nil {
+						return err
+					}
+					log.Println("キーストアに保存しました。", args[0])
+					return nil
+				}),
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,507 @@
+// ScrapingTaskManagerを設定ファイル、環境変数とフラグから組み立てて、サブコマンドを実行するコマンド。
+//
+// example:
+//
+//	LOGIN_USERNAME=yourname LOGIN_PASSWORD=password go run ./cmd/dlsite-scraper -config dlsite.yaml login
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	tasks "github.com/KatsutoshiOtogawa/dlsite_scraping_go"
+)
+
+// サブコマンドです。
+// 実行するタスクを返します。argsはサブコマンドの後ろに続く引数です。
+type command struct {
+	usage   string
+	offline bool // ブラウザも設定ファイルも使わない
+	run     func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error)
+}
+
+var commands = map[string]command{
+	"login": {
+		usage: "サイトにログインする",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			return chromedp.Tasks{
+				s.LoginSiteTasks(),
+			}, nil
+		},
+	},
+	"logout": {
+		usage: "サイトからログアウトする。-user-data-dirでログイン済みのプロファイルを指定すること",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			return chromedp.Tasks{
+				s.MoveTopPageTasks(),
+				s.LogoutTasks(),
+			}, nil
+		},
+	},
+	"top": {
+		usage: "トップページに移動してスクリーンショットをとる",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			return chromedp.Tasks{
+				s.MoveTopPageTasks(),
+				s.TakeScreenShotLogTasks("html", "top", "png"),
+			}, nil
+		},
+	},
+	"age-verify": {
+		usage: "年齢認証を突破する",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			return chromedp.Tasks{
+				s.AgeVerificationTasks(),
+				s.TakeScreenShotLogTasks("html", "age-verify", "png"),
+			}, nil
+		},
+	},
+	"work": {
+		usage: "work <productID> 作品ページの情報をjsonで出力する",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("作品IDを指定してください。")
+			}
+			var work tasks.Work
+			return chromedp.Tasks{
+				s.ScrapeWorkTasks(args[0], &work),
+				writeJSON(&work),
+			}, nil
+		},
+	},
+	"search": {
+		usage: "search [-keyword ...] [-max-pages n] 検索結果の作品の一覧をjsonで出力する",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			query, err := parseSearchQuery(args)
+			if err != nil {
+				return nil, err
+			}
+			var works []tasks.WorkSummary
+			return chromedp.Tasks{
+				s.SearchTasks(query, &works),
+				writeJSON(&works),
+			}, nil
+		},
+	},
+	"ranking": {
+		usage: "ranking [-category voice] [-period day] [-dir path] ランキングを保存して前回からの変化を出力する",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			fs := flag.NewFlagSet("ranking", flag.ContinueOnError)
+			category := fs.String("category", "", "作品形式(voice, comicなど)。空なら全体")
+			period := fs.String("period", tasks.RankingDay, "集計期間(day, week, month, year, total)")
+			dir := fs.String("dir", ".devcontainer/logs/ranking", "スナップショットの保存先")
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+
+			var entries []tasks.RankEntry
+			return chromedp.Tasks{
+				s.RankingTasks(*category, *period, &entries),
+				chromedp.ActionFunc(func(ctx context.Context) error {
+					snapshot := tasks.RankingSnapshot{
+						Category: *category,
+						Period:   *period,
+						TakenAt:  time.Now(),
+						Entries:  entries,
+					}
+					prev, ok, err := tasks.LatestRankingSnapshot(*dir, *category, *period, snapshot.TakenAt)
+					if err != nil {
+						return err
+					}
+					if _, err := s.SaveRankingSnapshot(*dir, snapshot); err != nil {
+						return err
+					}
+					if !ok {
+						log.Println("前回のランキングが無いので、変化は出力しません。")
+						return nil
+					}
+					printRankMoves(tasks.DiffRanking(prev.Entries, entries))
+					return nil
+				}),
+			}, nil
+		},
+	},
+	"purchases": {
+		usage: "purchases [-o file] [-format csv|json] [-no-login] 購入済みの作品を書き出す",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			fs := flag.NewFlagSet("purchases", flag.ContinueOnError)
+			out := exportFlags(fs)
+			login := loginFlags(fs)
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+
+			var ps []tasks.Purchase
+			return chromedp.Tasks{
+				login.tasks(s),
+				s.PurchaseHistoryTasks(&ps),
+				chromedp.ActionFunc(func(ctx context.Context) error {
+					return export(out, ps)
+				}),
+			}, nil
+		},
+	},
+	"wishlist": {
+		usage: "wishlist [-o file] [-format csv|json] [-no-login] お気に入りの作品を今の価格と一緒に書き出す",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			fs := flag.NewFlagSet("wishlist", flag.ContinueOnError)
+			out := exportFlags(fs)
+			login := loginFlags(fs)
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+
+			var ws []tasks.WishlistItem
+			return chromedp.Tasks{
+				login.tasks(s),
+				s.WishlistTasks(&ws),
+				chromedp.ActionFunc(func(ctx context.Context) error {
+					return export(out, ws)
+				}),
+			}, nil
+		},
+	},
+	"screenshot": {
+		usage: "screenshot [-format png] [-full] <url> [selector] 指定したページのスクリーンショットをとる",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			fs := flag.NewFlagSet("screenshot", flag.ContinueOnError)
+			format := fs.String("format", "png", "形式(png, jpg, webp, pdf)。jpg, webpの画質はSCREENSHOT_QUALITY")
+			full := fs.Bool("full", false, "表示されていない部分も含めてページ全体を撮る")
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+			args = fs.Args()
+			if len(args) == 0 {
+				return nil, fmt.Errorf("urlを指定してください。")
+			}
+			var sel interface{} = "html"
+			if len(args) > 1 {
+				sel = args[1]
+			} else if *full {
+				sel = nil
+			}
+			return chromedp.Tasks{
+				s.MovePageTasks(args[0]),
+				s.TakeScreenShotLogTasks(sel, "screenshot", *format),
+			}, nil
+		},
+	},
+}
+
+// 取得した値をjsonで標準出力に書き出すタスク。
+// 値はタスクの実行中に埋まるので、ポインタを渡すこと。
+func writeJSON(v interface{}) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
+
+// searchコマンドの引数を検索条件にする。
+func parseSearchQuery(args []string) (tasks.SearchQuery, error) {
+	var query tasks.SearchQuery
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.StringVar(&query.Keyword, "keyword", "", "キーワード")
+	genres := fs.String("genre", "", "ジャンルのID。カンマ区切りで複数指定できる")
+	fs.StringVar(&query.Circle, "circle", "", "サークル名、メーカー名")
+	fs.IntVar(&query.PriceLow, "price-low", 0, "価格の下限")
+	fs.IntVar(&query.PriceHigh, "price-high", 0, "価格の上限")
+	from := fs.String("from", "", "販売日の下限(2006-01-02)")
+	to := fs.String("to", "", "販売日の上限(2006-01-02)")
+	fs.StringVar(&query.Order, "order", tasks.SearchOrderTrend, "並び順(trend, release_d, release, dl_d, price, price_d, rate_d, review_d)")
+	fs.IntVar(&query.MaxPages, "max-pages", 1, "辿るページ数の上限。0なら最後のページまで")
+	if err := fs.Parse(args); err != nil {
+		return query, err
+	}
+
+	if *genres != "" {
+		query.Genres = strings.Split(*genres, ",")
+	}
+	var err error
+	if *from != "" {
+		if query.ReleaseFrom, err = time.Parse("2006-01-02", *from); err != nil {
+			return query, fmt.Errorf("-fromを解釈できませんでした。: %w", err)
+		}
+	}
+	if *to != "" {
+		if query.ReleaseTo, err = time.Parse("2006-01-02", *to); err != nil {
+			return query, fmt.Errorf("-toを解釈できませんでした。: %w", err)
+		}
+	}
+	return query, nil
+}
+
+// 順位の変化を標準出力に書き出す。
+func printRankMoves(moves []tasks.RankMove) {
+	for _, m := range moves {
+		var change string
+		switch {
+		case m.New():
+			change = "new"
+		case m.Dropped():
+			change = "out"
+		case m.Change > 0:
+			change = fmt.Sprintf("+%d", m.Change)
+		case m.Change < 0:
+			change = fmt.Sprintf("%d", m.Change)
+		default:
+			change = "="
+		}
+		fmt.Printf("%4d %5s %s %s\n", m.Rank, change, m.ProductID, m.Title)
+	}
+}
+
+// ログインが必要なコマンドのログインの仕方です。
+type loginOptions struct {
+	noLogin bool
+}
+
+func loginFlags(fs *flag.FlagSet) *loginOptions {
+	var o loginOptions
+	fs.BoolVar(&o.noLogin, "no-login", false, "ログインしない。-user-data-dirでログイン済みのときに使う")
+	return &o
+}
+
+// コマンドの前にログインするタスク。ログイン済みならログインしない。
+func (o *loginOptions) tasks(s tasks.ScrapingTaskManager) chromedp.Tasks {
+	if o.noLogin {
+		return chromedp.Tasks{}
+	}
+	return s.EnsureLoggedInTasks()
+}
+
+// 保存したcookieを読み込む。
+// 初回の実行でファイルが無い場合や、期限が切れている場合は読み込まずに続ける。
+func loadCookies(s tasks.ScrapingTaskManager, path string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		err := s.LoadCookiesTasks(path).Do(ctx)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, tasks.ErrSessionExpired) {
+			log.Println("保存したcookieを使わずに続けます。", err)
+			return nil
+		}
+		return err
+	})
+}
+
+// 一覧の書き出し先です。
+type exportOptions struct {
+	path   string
+	format string
+}
+
+func exportFlags(fs *flag.FlagSet) *exportOptions {
+	var o exportOptions
+	fs.StringVar(&o.path, "o", "", "書き出し先のファイル(.csv, .json)。空なら標準出力")
+	fs.StringVar(&o.format, "format", "json", "標準出力に書き出すときの形式(csv, json)")
+	return &o
+}
+
+// 一覧をファイルか標準出力に書き出す。
+func export[T tasks.Record](o *exportOptions, items []T) error {
+	if o.path == "" {
+		return tasks.Export(os.Stdout, o.format, items)
+	}
+	if err := tasks.ExportFile(o.path, items); err != nil {
+		return err
+	}
+	log.Printf("%d件を%sに書き出しました。", len(items), o.path)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <command> [args]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-12s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+// mainの中身。deferで開いたものを閉じてから終了できるように、エラーを返す。
+func run() error {
+	configPath := flag.String("config", "", "設定ファイル(.yaml, .toml, .json)。環境変数で上書きされる")
+	profile := flag.String("profile", "", "サイトのプロファイル(dlsite, home, maniax, books, proか設定ファイルのProfiles)")
+	headless := flag.Bool("headless", true, "ヘッドレスモードで実行する")
+	timeout := flag.Duration("timeout", 15*time.Minute, "全体のタイムアウト。小さすぎるとcontext deadline exceededになる")
+	wait := flag.Duration("wait", 0, "処理ごとに待つ時間。0なら設定のDefaultTimeSpanを使う")
+	taskTimeout := flag.Duration("task-timeout", 0, "処理1つごとのタイムアウト。0なら設定のTaskTimeoutを使う")
+	retry := flag.Int("retry", 0, "移動、クリック、テキストの取得が失敗したときに、やり直しも含めて実行する回数。0なら設定のRETRY_MAX_ATTEMPTSを使う")
+	retryReload := flag.Bool("retry-reload", false, "やり直す前にページを再読み込みする")
+	waitFor := flag.String("wait-for", "", "処理の後に待つ条件(network-idle, load, domcontentloaded, selector=, url=, js=)。指定しなければ-waitの時間だけ待つ")
+	waitTimeout := flag.Duration("wait-timeout", 30*time.Second, "-wait-forの条件を待つ時間の上限")
+	width := flag.Int64("width", 0, "ウィンドウの幅。0なら設定のWidthを使う")
+	height := flag.Int64("height", 0, "ウィンドウの高さ。0なら設定のHeightを使う")
+	userDataDir := flag.String("user-data-dir", "", "chromeのプロファイルの保存先。指定するとログイン状態を引き継げる")
+	cookies := flag.String("cookies", "", "cookieの保存先。指定すると実行前に読み込んで、実行後に保存する。鍵はCOOKIE_SECRET")
+	otpPrompt := flag.Bool("otp-prompt", false, "2段階認証のコードを標準入力から読む。LOGIN_TOTP_SECRETがあればそちらを使う")
+	priceDB := flag.String("price-db", "", "価格の履歴の保存先。指定すると作品の価格を記録する")
+	captureFailure := flag.Bool("capture-failure", false, "処理が失敗したときに、スクリーンショット、url、html、コンソールのログをSCREENSHOT_LOG_PATHに保存する")
+	traceDir := flag.String("trace-dir", "", "指定すると、実行した処理とスクリーンショットをこの下に記録してreport.htmlを作る")
+	logFile := flag.String("log-file", "", "ログをjsonで1行ずつ追記するファイル。指定しなければ標準エラー出力に出す")
+	var logLevel slog.Level
+	flag.TextVar(&logLevel, "log-level", slog.LevelInfo, "出力するログのレベル(DEBUG, INFO, WARN, ERROR)")
+	flag.Usage = usage
+	flag.Parse()
+
+	// まだ何も開いていないので、そのまま終了して良い。
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "不明なコマンドです。: %s\n\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 標準出力は結果のjsonに使うので、ログはファイルか標準エラー出力に出す。
+	if *logFile != "" {
+		logger, closer, err := tasks.NewJSONFileLogger(*logFile, logLevel)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		slog.SetDefault(logger)
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	}
+
+	var priceStore *tasks.PriceStore
+	if *priceDB != "" {
+		var err error
+		priceStore, err = tasks.OpenPriceStore(*priceDB)
+		if err != nil {
+			return err
+		}
+		defer priceStore.Close()
+	}
+
+	if cmd.offline {
+		actions, err := cmd.run(tasks.ScrapingTaskManager{PriceStore: priceStore}, flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+		return actions.Do(context.Background())
+	}
+
+	s, err := tasks.LoadProfileConfig(*configPath, *profile)
+	if err != nil {
+		return err
+	}
+	s.PriceStore = priceStore
+	if *wait > 0 {
+		s.DefaultTimeSpan = *wait
+	}
+	if *taskTimeout > 0 {
+		s.TaskTimeout = *taskTimeout
+	}
+	if *retry > 0 {
+		// 設定ファイルのRetryPolicyがあれば、回数だけ変える。
+		if s.RetryPolicy == nil {
+			s.RetryPolicy = &tasks.RetryPolicy{Jitter: 0.2}
+		}
+		s.RetryPolicy.MaxAttempts = *retry
+	}
+	if *retryReload && s.RetryPolicy != nil {
+		s.RetryPolicy.Reload = true
+	}
+	if *waitFor != "" {
+		s.WaitLogic, err = tasks.ParseWaitLogic(*waitFor, *waitTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	if *width > 0 {
+		s.Width = *width
+	}
+	if *height > 0 {
+		s.Height = *height
+	}
+	if s.Width == 0 || s.Height == 0 {
+		s.Width, s.Height = 1280, 1024
+	}
+
+	if *cookies != "" {
+		s.CookieFilePath = *cookies
+	}
+	if *otpPrompt && s.OTPProvider == nil {
+		s.OTPProvider = tasks.PromptOTP(os.Stdin, os.Stderr)
+	}
+	if *captureFailure {
+		s.FailureCapture = &tasks.FailureCapture{}
+	}
+	if *traceDir != "" {
+		s.Trace, err = tasks.NewTrace(*traceDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	actions, err := cmd.run(s, flag.Args()[1:])
+	if err != nil {
+		return err
+	}
+	if *cookies != "" {
+		actions = chromedp.Tasks{
+			loadCookies(s, *cookies),
+			actions,
+			s.SaveCookiesTasks(*cookies),
+		}
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", *headless),
+		chromedp.WindowSize(int(s.Width), int(s.Height)),
+	)
+	if *userDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(*userDataDir))
+	}
+	ctx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	defer cancel()
+
+	ctx, cancel = chromedp.NewContext(ctx)
+	defer cancel()
+
+	ctx, cancel = context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	err = chromedp.Run(ctx,
+		s.EmulateViewportTasks(s.Width, s.Height),
+		actions,
+	)
+	// 失敗したときこそ見たいので、エラーでもトレースは書き出す。
+	if s.Trace != nil {
+		if err := s.Trace.Close(); err != nil {
+			log.Println(err)
+		} else {
+			log.Println("トレースを保存しました。", filepath.Join(s.Trace.Dir, "report.html"))
+		}
+	}
+	return err
+}
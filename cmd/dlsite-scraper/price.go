@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	tasks "github.com/KatsutoshiOtogawa/dlsite_scraping_go"
+)
+
+func init() {
+	commands["price-history"] = command{
+		usage:   "price-history <productID> -price-dbに記録した作品の価格の履歴をjsonで出力する",
+		offline: true,
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			if s.PriceStore == nil {
+				return nil, fmt.Errorf("-price-dbを指定してください。")
+			}
+			if len(args) == 0 {
+				return nil, fmt.Errorf("作品IDを指定してください。")
+			}
+			var history []tasks.PricePoint
+			return chromedp.Tasks{
+				chromedp.ActionFunc(func(ctx context.Context) (err error) {
+					history, err = s.PriceStore.PriceHistory(args[0])
+					return err
+				}),
+				writeJSON(&history),
+			}, nil
+		},
+	}
+	commands["sales"] = command{
+		usage:   "sales [-since 24h] -price-dbに記録した価格から、始まったセールをjsonで出力する",
+		offline: true,
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			if s.PriceStore == nil {
+				return nil, fmt.Errorf("-price-dbを指定してください。")
+			}
+			fs := flag.NewFlagSet("sales", flag.ContinueOnError)
+			since := fs.Duration("since", 24*time.Hour, "どれだけ前からのセールを出力するか")
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+
+			var events []tasks.SaleEvent
+			return chromedp.Tasks{
+				chromedp.ActionFunc(func(ctx context.Context) (err error) {
+					events, err = s.DetectSales(time.Now().Add(-*since))
+					return err
+				}),
+				writeJSON(&events),
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+
+	tasks "github.com/KatsutoshiOtogawa/dlsite_scraping_go"
+)
+
+func init() {
+	commands["check-selectors"] = command{
+		usage: "check-selectors [-work RJ01000000] 設定したSelectorが各ページで1つの要素に合致するか調べる",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			fs := flag.NewFlagSet("check-selectors", flag.ContinueOnError)
+			work := fs.String("work", "", "作品ページを調べるときの作品ID。空なら作品ページは調べない")
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+
+			var reports []tasks.SelectorReport
+			return chromedp.Tasks{
+				s.CheckSelectorsTasks(*work, &reports),
+				writeJSON(&reports),
+				// 合っていないSelectorがあれば、終了コードで分かるようにする。
+				chromedp.ActionFunc(func(ctx context.Context) error {
+					n := 0
+					for _, r := range reports {
+						if r.Status != tasks.SelectorOK {
+							n++
+						}
+					}
+					if n > 0 {
+						return fmt.Errorf("%d個のSelectorが合っていません。", n)
+					}
+					return nil
+				}),
+			}, nil
+		},
+	}
+}
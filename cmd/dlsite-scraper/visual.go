@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+
+	tasks "github.com/KatsutoshiOtogawa/dlsite_scraping_go"
+)
+
+func init() {
+	commands["visual-check"] = command{
+		usage: "visual-check [-update] [-threshold 0.01] <checkpoint> <url> [selector] ページの見た目をベースラインと比べる",
+		run: func(s tasks.ScrapingTaskManager, args []string) (chromedp.Tasks, error) {
+			fs := flag.NewFlagSet("visual-check", flag.ContinueOnError)
+			v := tasks.VisualCheck{}
+			fs.StringVar(&v.Dir, "dir", "", "ベースラインと差分の画像の保存先。空ならSCREENSHOT_LOG_PATHの下のvisual")
+			fs.Float64Var(&v.Threshold, "threshold", 0.01, "変わったピクセルの割合の上限")
+			fs.Float64Var(&v.Tolerance, "tolerance", 0.1, "同じ色とみなす色の差。0から1")
+			fs.BoolVar(&v.Update, "update", false, "比べずに今の画面でベースラインを置き換える")
+			mask := fs.String("mask", "", "比べる前に隠す要素のSelector。カンマ区切りで複数指定できる")
+			if err := fs.Parse(args); err != nil {
+				return nil, err
+			}
+			args = fs.Args()
+			if len(args) < 2 {
+				return nil, fmt.Errorf("チェックポイントの名前とurlを指定してください。")
+			}
+			if *mask != "" {
+				for _, m := range strings.Split(*mask, ",") {
+					v.Mask = append(v.Mask, tasks.Selector(m))
+				}
+			}
+			var sel interface{}
+			if len(args) > 2 {
+				sel = args[2]
+			}
+
+			s.VisualCheck = &v
+			var result tasks.VisualResult
+			return chromedp.Tasks{
+				s.MovePageTasks(args[1]),
+				s.VisualCheckTasks(args[0], sel, &result),
+				writeJSON(&result),
+			}, nil
+		},
+	}
+}
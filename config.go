@@ -0,0 +1,361 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定ファイルで使う時間の型です。
+// "3s", "500ms"のようなtime.ParseDurationの書式と、秒数の整数を受け付けます。
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := parseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(time.Duration(v * float64(time.Second)))
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	}
+	return fmt.Errorf("時間として解釈できませんでした。: %s", b)
+}
+
+// .devcontainer/.envのDEFAULT_TIME_SPANのような秒数の整数も受け付ける。
+func parseDuration(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if num, err := strconv.Atoi(v); err == nil {
+		return time.Duration(num) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("時間として解釈できませんでした。: %w", err)
+	}
+	return d, nil
+}
+
+// 設定ファイルの中身です。
+// キーはScrapingTaskManagerのフィールド名と同じ。
+// Profileでサイトのプロファイルを選ぶと、ファイルに書いていないフィールドはプロファイルの値になる。
+type configFile struct {
+	ScrapingTaskManager
+	DefaultTimeSpan Duration
+	CookieMaxAge    Duration
+	TaskTimeout     Duration
+	Profile         string                 // 使うプロファイルの名前
+	Profiles        map[string]SiteProfile // ユーザー定義のプロファイル
+
+	// 時間をDurationで書けるようにしたもの。
+	RetryPolicy    *retryPolicyConfig
+	FailureCapture *failureCaptureConfig
+}
+
+// 設定ファイルのRetryPolicyです。
+type retryPolicyConfig struct {
+	RetryPolicy
+	Backoff    Duration
+	MaxBackoff Duration
+}
+
+func (c retryPolicyConfig) policy() *RetryPolicy {
+	p := c.RetryPolicy
+	p.Backoff = time.Duration(c.Backoff)
+	p.MaxBackoff = time.Duration(c.MaxBackoff)
+	return &p
+}
+
+// 設定ファイルのFailureCaptureです。
+type failureCaptureConfig struct {
+	Dir        string
+	MaxConsole int
+	Timeout    Duration
+}
+
+func (c failureCaptureConfig) capture() *FailureCapture {
+	return &FailureCapture{Dir: c.Dir, MaxConsole: c.MaxConsole, Timeout: time.Duration(c.Timeout)}
+}
+
+// パスワードの取得の仕方を選ぶ環境変数です。同時には1つしか設定できない。
+var passwordEnvs = []string{"LOGIN_PASSWORD", "LOGIN_PASSWORD_FILE", "LOGIN_PASSWORD_COMMAND", "LOGIN_KEYSTORE"}
+
+// 環境変数による上書きです。
+// 名前は.devcontainer/.envと同じ。空文字の環境変数は上書きしない。
+var configEnvs = []struct {
+	name string
+	set  func(s *ScrapingTaskManager, v string) error
+}{
+	{"SITE_SESSION_COOKIE", func(s *ScrapingTaskManager, v string) error { s.SiteSessionCookieName = v; return nil }},
+	{"SITE_TOP_URL", func(s *ScrapingTaskManager, v string) error { s.SiteTopUrl = v; return nil }},
+	{"SCREENSHOT_LOG_PATH", func(s *ScrapingTaskManager, v string) error { s.ScreenShotLogPath = v; return nil }},
+	{"SCREENSHOT_LOG_PREFIX", func(s *ScrapingTaskManager, v string) error { s.ScreenShotLogPrefix = v; return nil }},
+	{"SCREENSHOT_QUALITY", func(s *ScrapingTaskManager, v string) (err error) {
+		s.ScreenShotQuality, err = strconv.Atoi(v)
+		return err
+	}},
+	{"LOGIN_URL", func(s *ScrapingTaskManager, v string) error { s.LogInUrl = v; return nil }},
+	{"LOGOUT_URL", func(s *ScrapingTaskManager, v string) error { s.LogOutUrl = v; return nil }},
+	{"LOGIN_USERNAME", func(s *ScrapingTaskManager, v string) error { s.LoginUsername = v; return nil }},
+	{"LOGIN_USERNAME_SEL", func(s *ScrapingTaskManager, v string) error { s.LoginUsernameSel = Selector(v); return nil }},
+	{"LOGIN_PASSWORD", func(s *ScrapingTaskManager, v string) error { s.LoginPassword = v; return nil }},
+	{"LOGIN_PASSWORD_FILE", func(s *ScrapingTaskManager, v string) error {
+		s.Credentials = FileCredentials{Path: v}
+		return nil
+	}},
+	{"LOGIN_PASSWORD_COMMAND", func(s *ScrapingTaskManager, v string) error {
+		// 引用符やパイプを書けるように、シェルで実行する。
+		s.Credentials = CommandCredentials{Command: []string{"sh", "-c", v}}
+		return nil
+	}},
+	{"LOGIN_KEYSTORE", func(s *ScrapingTaskManager, v string) error {
+		s.Credentials = KeystoreCredentials{Path: v, Secret: os.Getenv("LOGIN_KEYSTORE_SECRET")}
+		return nil
+	}},
+	{"LOGIN_PASSWORD_SEL", func(s *ScrapingTaskManager, v string) error { s.LoginPasswordSel = Selector(v); return nil }},
+	{"LOGIN_BUTTON_SEL", func(s *ScrapingTaskManager, v string) error { s.LoginButtonSel = Selector(v); return nil }},
+	{"LOGIN_OTP_BUTTON_SEL", func(s *ScrapingTaskManager, v string) error { s.LoginOTPButtonSel = Selector(v); return nil }},
+	{"LOGIN_TOTP_SECRET", func(s *ScrapingTaskManager, v string) error { s.OTPProvider = TOTP{Secret: v}; return nil }},
+	{"AGE_PERMISSION_URL", func(s *ScrapingTaskManager, v string) error { s.AgePermissionUrl = v; return nil }},
+	{"AGE_PERMISSION_SEL", func(s *ScrapingTaskManager, v string) error { s.AgePermissionSel = Selector(v); return nil }},
+	{"AGE_PERMISSION_NEXT_SEL", func(s *ScrapingTaskManager, v string) error { s.AgePermissionNextSel = Selector(v); return nil }},
+	{"DEFAULT_TIME_SPAN", func(s *ScrapingTaskManager, v string) (err error) {
+		s.DefaultTimeSpan, err = parseDuration(v)
+		return err
+	}},
+	{"TASK_TIMEOUT", func(s *ScrapingTaskManager, v string) (err error) {
+		s.TaskTimeout, err = parseDuration(v)
+		return err
+	}},
+	{"COOKIE_SECRET", func(s *ScrapingTaskManager, v string) error { s.CookieSecret = v; return nil }},
+	{"COOKIE_MAX_AGE", func(s *ScrapingTaskManager, v string) (err error) {
+		s.CookieMaxAge, err = parseDuration(v)
+		return err
+	}},
+	{"COOKIE_FILE", func(s *ScrapingTaskManager, v string) error { s.CookieFilePath = v; return nil }},
+	{"LOGIN_RETRIES", func(s *ScrapingTaskManager, v string) (err error) {
+		s.LoginRetries, err = strconv.Atoi(v)
+		return err
+	}},
+	{"RETRY_MAX_ATTEMPTS", func(s *ScrapingTaskManager, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		// 設定ファイルのRetryPolicyがあれば、回数だけ変える。
+		if s.RetryPolicy == nil {
+			s.RetryPolicy = &RetryPolicy{Jitter: 0.2}
+		}
+		s.RetryPolicy.MaxAttempts = n
+		return nil
+	}},
+	{"WIDTH", func(s *ScrapingTaskManager, v string) (err error) {
+		s.Width, err = strconv.ParseInt(v, 10, 64)
+		return err
+	}},
+	{"HEIGHT", func(s *ScrapingTaskManager, v string) (err error) {
+		s.Height, err = strconv.ParseInt(v, 10, 64)
+		return err
+	}},
+}
+
+// 設定が足りないか、値が正しくないときのエラーです。
+// 足りない設定と正しくない設定を全て持っています。
+type ConfigError struct {
+	Missing []string
+	Invalid []string // 正しくない設定の名前と理由
+}
+
+func (e *ConfigError) Error() string {
+	var msgs []string
+	if len(e.Missing) > 0 {
+		msgs = append(msgs, fmt.Sprintf("必須の設定がありません。: %s", strings.Join(e.Missing, ", ")))
+	}
+	if len(e.Invalid) > 0 {
+		msgs = append(msgs, fmt.Sprintf("設定の値が正しくありません。: %s", strings.Join(e.Invalid, ", ")))
+	}
+	return strings.Join(msgs, " ")
+}
+
+// 設定ファイルを読み込んで、環境変数で上書きしたScrapingTaskManagerを返す。
+// 拡張子で形式を判断する。.yaml, .yml, .toml, .jsonに対応。
+// pathが空文字の場合は環境変数だけを使う。
+// Loggerはnilなのでslog.Default()に出る。必要なら後から差し替えること。
+func LoadConfig(path string) (ScrapingTaskManager, error) {
+	return LoadProfileConfig(path, "")
+}
+
+// LoadConfigと同じだが、使うサイトのプロファイルを指定する。
+// プロファイルは引数、環境変数SITE_PROFILE、設定ファイルのProfileの順に優先される。
+// 全て空ならプロファイルは使わない。
+func LoadProfileConfig(path string, profile string) (ScrapingTaskManager, error) {
+	var cfg configFile
+	var b []byte
+	if path != "" {
+		var err error
+		b, err = readConfigFile(path)
+		if err != nil {
+			return ScrapingTaskManager{}, err
+		}
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return ScrapingTaskManager{}, fmt.Errorf("設定ファイルを解釈できませんでした。: %w", err)
+		}
+	}
+
+	if profile == "" {
+		profile = os.Getenv("SITE_PROFILE")
+	}
+	if profile == "" {
+		profile = cfg.Profile
+	}
+	if profile != "" {
+		p, err := ResolveProfile(profile, cfg.Profiles)
+		if err != nil {
+			return ScrapingTaskManager{}, err
+		}
+		// プロファイルの値を入れてから、設定ファイルに書かれているものだけ上書きする。
+		cfg.ScrapingTaskManager = ScrapingTaskManager{}.WithProfile(p)
+		if b != nil {
+			if err := json.Unmarshal(b, &cfg); err != nil {
+				return ScrapingTaskManager{}, fmt.Errorf("設定ファイルを解釈できませんでした。: %w", err)
+			}
+		}
+	}
+
+	s := cfg.ScrapingTaskManager
+	s.DefaultTimeSpan = time.Duration(cfg.DefaultTimeSpan)
+	s.CookieMaxAge = time.Duration(cfg.CookieMaxAge)
+	s.TaskTimeout = time.Duration(cfg.TaskTimeout)
+	if cfg.RetryPolicy != nil {
+		s.RetryPolicy = cfg.RetryPolicy.policy()
+	}
+	if cfg.FailureCapture != nil {
+		s.FailureCapture = cfg.FailureCapture.capture()
+	}
+
+	if err := s.applyEnv(); err != nil {
+		return ScrapingTaskManager{}, err
+	}
+
+	if err := s.Validate(); err != nil {
+		return ScrapingTaskManager{}, err
+	}
+	return s, nil
+}
+
+// 設定ファイルを読み込む。
+// 形式を揃えるために、jsonに変換して返す。
+func readConfigFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルを読み込めませんでした。: %w", err)
+	}
+
+	var m map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &m)
+	case ".toml":
+		err = toml.Unmarshal(b, &m)
+	case ".json":
+		err = json.Unmarshal(b, &m)
+	default:
+		return nil, fmt.Errorf("対応していない設定ファイルの形式です。: %s", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルを解釈できませんでした。: %w", err)
+	}
+
+	b, err = json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルを解釈できませんでした。: %w", err)
+	}
+	return b, nil
+}
+
+// 環境変数で上書きする。
+func (s *ScrapingTaskManager) applyEnv() error {
+	// 後から設定したものが使われて、どれが使われたかわからなくなるので、パスワードの取得の仕方は1つだけにする。
+	var passwords []string
+	for _, name := range passwordEnvs {
+		if os.Getenv(name) != "" {
+			passwords = append(passwords, name)
+		}
+	}
+	if len(passwords) > 1 {
+		return &ConfigError{Invalid: []string{fmt.Sprintf("パスワードの環境変数は1つだけ設定してください。(%s)", strings.Join(passwords, ", "))}}
+	}
+
+	var errs []error
+	for _, env := range configEnvs {
+		v := os.Getenv(env.name)
+		if v == "" {
+			continue
+		}
+		if err := env.set(s, v); err != nil {
+			errs = append(errs, fmt.Errorf("%sを解釈できませんでした。: %w", env.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 必須のurlとセレクタが設定されているか確認する。
+// 足りないものがあれば*ConfigErrorを返す。
+func (s ScrapingTaskManager) Validate() error {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"SiteSessionCookieName", s.SiteSessionCookieName},
+		{"SiteTopUrl", s.SiteTopUrl},
+		{"LogInUrl", s.LogInUrl},
+		{"LogOutUrl", s.LogOutUrl},
+		{"LoginUsernameSel", string(s.LoginUsernameSel)},
+		{"LoginPasswordSel", string(s.LoginPasswordSel)},
+		{"LoginButtonSel", string(s.LoginButtonSel)},
+		{"AgePermissionUrl", s.AgePermissionUrl},
+		{"AgePermissionSel", string(s.AgePermissionSel)},
+		{"AgePermissionNextSel", string(s.AgePermissionNextSel)},
+	}
+
+	var missing []string
+	for _, r := range required {
+		if r.value == "" {
+			missing = append(missing, r.name)
+		}
+	}
+
+	var invalid []string
+	// 1より大きいと、待つ時間がマイナスになることがある。
+	if p := s.RetryPolicy; p != nil && (p.Jitter < 0 || p.Jitter > 1) {
+		invalid = append(invalid, fmt.Sprintf("RetryPolicy.Jitterは0から1にしてください。(%v)", p.Jitter))
+	}
+	// 0はデフォルトの90になる。
+	if s.ScreenShotQuality < 0 || s.ScreenShotQuality > 100 {
+		invalid = append(invalid, fmt.Sprintf("ScreenShotQualityは0から100にしてください。(%d)", s.ScreenShotQuality))
+	}
+
+	if len(missing) > 0 || len(invalid) > 0 {
+		return &ConfigError{Missing: missing, Invalid: invalid}
+	}
+	return nil
+}
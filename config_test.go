@@ -0,0 +1,441 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// テスト中に.devcontainer/.envの環境変数が混ざらないようにする。
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range configEnvs {
+		t.Setenv(env.name, "")
+	}
+	t.Setenv("SITE_PROFILE", "")
+}
+
+func writeConfig(t *testing.T, name string, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 形式ごとに設定ファイルが読み込めるか確認。
+func TestLoadConfig(t *testing.T) {
+	clearConfigEnv(t)
+
+	tests := []struct {
+		name string
+		file string
+		body string
+	}{
+		{
+			name: "yaml",
+			file: "dlsite.yaml",
+			body: `
+SiteSessionCookieName: session_state
+SiteTopUrl: https://www.dlsite.com/
+LogInUrl: https://login.dlsite.com/login
+LogOutUrl: https://www.dlsite.com/home/logout
+LoginUsernameSel: "#form_id"
+LoginPasswordSel: "#form_password"
+LoginButtonSel: button
+AgePermissionUrl: https://www.dlsite.com/maniax/
+AgePermissionSel: .btn_yes a
+AgePermissionNextSel: "#top_header"
+DefaultTimeSpan: 3s
+Width: 1280
+`,
+		},
+		{
+			name: "toml",
+			file: "dlsite.toml",
+			body: `
+SiteSessionCookieName = "session_state"
+SiteTopUrl = "https://www.dlsite.com/"
+LogInUrl = "https://login.dlsite.com/login"
+LogOutUrl = "https://www.dlsite.com/home/logout"
+LoginUsernameSel = "#form_id"
+LoginPasswordSel = "#form_password"
+LoginButtonSel = "button"
+AgePermissionUrl = "https://www.dlsite.com/maniax/"
+AgePermissionSel = ".btn_yes a"
+AgePermissionNextSel = "#top_header"
+DefaultTimeSpan = "3s"
+Width = 1280
+`,
+		},
+		{
+			name: "json",
+			file: "dlsite.json",
+			body: `{
+	"SiteSessionCookieName": "session_state",
+	"SiteTopUrl": "https://www.dlsite.com/",
+	"LogInUrl": "https://login.dlsite.com/login",
+	"LogOutUrl": "https://www.dlsite.com/home/logout",
+	"LoginUsernameSel": "#form_id",
+	"LoginPasswordSel": "#form_password",
+	"LoginButtonSel": "button",
+	"AgePermissionUrl": "https://www.dlsite.com/maniax/",
+	"AgePermissionSel": ".btn_yes a",
+	"AgePermissionNextSel": "#top_header",
+	"DefaultTimeSpan": 3,
+	"Width": 1280
+}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadConfig(writeConfig(t, tt.file, tt.body))
+			if err != nil {
+				t.Fatalf("LoadConfig() = %v", err)
+			}
+			if s.SiteTopUrl != "https://www.dlsite.com/" {
+				t.Errorf("LoadConfig() SiteTopUrl = %s, want %s", s.SiteTopUrl, "https://www.dlsite.com/")
+			}
+			if s.AgePermissionSel != ".btn_yes a" {
+				t.Errorf("LoadConfig() AgePermissionSel = %s, want %s", s.AgePermissionSel, ".btn_yes a")
+			}
+			if s.DefaultTimeSpan != 3*time.Second {
+				t.Errorf("LoadConfig() DefaultTimeSpan = %v, want %v", s.DefaultTimeSpan, 3*time.Second)
+			}
+			if s.Width != 1280 {
+				t.Errorf("LoadConfig() Width = %v, want %v", s.Width, 1280)
+			}
+		})
+	}
+}
+
+// 環境変数で上書きされるか確認。
+func TestLoadConfigEnv(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := writeConfig(t, "dlsite.yaml", `
+SiteSessionCookieName: session_state
+SiteTopUrl: https://www.dlsite.com/
+LogInUrl: https://login.dlsite.com/login
+LogOutUrl: https://www.dlsite.com/home/logout
+LoginUsernameSel: "#form_id"
+LoginPasswordSel: "#form_password"
+LoginButtonSel: button
+AgePermissionUrl: https://www.dlsite.com/maniax/
+AgePermissionSel: .btn_yes a
+AgePermissionNextSel: "#top_header"
+DefaultTimeSpan: 3s
+`)
+	t.Setenv("SITE_TOP_URL", "https://www.dlsite.com/maniax/")
+	t.Setenv("DEFAULT_TIME_SPAN", "2")
+
+	s, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	if s.SiteTopUrl != "https://www.dlsite.com/maniax/" {
+		t.Errorf("LoadConfig() SiteTopUrl = %s, want %s", s.SiteTopUrl, "https://www.dlsite.com/maniax/")
+	}
+	if s.DefaultTimeSpan != 2*time.Second {
+		t.Errorf("LoadConfig() DefaultTimeSpan = %v, want %v", s.DefaultTimeSpan, 2*time.Second)
+	}
+}
+
+// 設定ファイルと環境変数に、解釈の仕方をつけたSelectorが書けるか確認。
+func TestLoadConfigSelector(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := writeConfig(t, "dlsite.yaml", `
+SiteSessionCookieName: session_state
+SiteTopUrl: https://www.dlsite.com/
+LogInUrl: https://login.dlsite.com/login
+LogOutUrl: https://www.dlsite.com/home/logout
+LoginUsernameSel: "#form_id"
+LoginPasswordSel: "#form_password"
+LoginButtonSel: button
+AgePermissionUrl: https://www.dlsite.com/maniax/
+AgePermissionSel: text=はい
+AgePermissionNextSel: "#top_header"
+WorkTitleSel: xpath=//h1[@id='work_name']
+`)
+	t.Setenv("LOGIN_BUTTON_SEL", "css=button.login")
+
+	s, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	tests := []struct {
+		name     string
+		got      Selector
+		wantBy   QueryType
+		wantExpr string
+	}{
+		{name: "text", got: s.AgePermissionSel, wantBy: QueryText, wantExpr: "はい"},
+		{name: "xpath", got: s.WorkTitleSel, wantBy: QueryXPath, wantExpr: "//h1[@id='work_name']"},
+		{name: "default", got: s.AgePermissionNextSel, wantBy: QueryDefault, wantExpr: "#top_header"},
+		{name: "env", got: s.LoginButtonSel, wantBy: QueryCSS, wantExpr: "button.login"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.By() != tt.wantBy || tt.got.Expr() != tt.wantExpr {
+				t.Errorf("LoadConfig() = %v, want %v", tt.got, NewSelector(tt.wantBy, tt.wantExpr))
+			}
+		})
+	}
+}
+
+// パスワードの環境変数から、Credentialsが設定されるか確認。
+func TestLoadConfigCredentials(t *testing.T) {
+	body := `
+SiteSessionCookieName: session_state
+SiteTopUrl: https://www.dlsite.com/
+LogInUrl: https://login.dlsite.com/login
+LogOutUrl: https://www.dlsite.com/home/logout
+LoginUsernameSel: "#form_id"
+LoginPasswordSel: "#form_password"
+LoginButtonSel: button
+AgePermissionUrl: https://www.dlsite.com/maniax/
+AgePermissionSel: .btn_yes a
+AgePermissionNextSel: "#top_header"
+`
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string // Credentialで取得できるパスワード
+		wantErr bool
+	}{
+		{name: "password", env: map[string]string{"LOGIN_PASSWORD": "secret"}, want: "secret"},
+		// 引用符で囲んだ引数は分けない。
+		{name: "command", env: map[string]string{"LOGIN_PASSWORD_COMMAND": `printf '%s\n' "pass word"`}, want: "pass word"},
+		{name: "password and command", env: map[string]string{"LOGIN_PASSWORD": "secret", "LOGIN_PASSWORD_COMMAND": "echo secret"}, wantErr: true},
+		{name: "file and keystore", env: map[string]string{"LOGIN_PASSWORD_FILE": "password", "LOGIN_KEYSTORE": "keystore"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			s, err := LoadConfig(writeConfig(t, "dlsite.yaml", body))
+			if tt.wantErr {
+				var cerr *ConfigError
+				if !errors.As(err, &cerr) || len(cerr.Invalid) != 1 {
+					t.Errorf("LoadConfig() error = %v, want *ConfigError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() = %v", err)
+			}
+			got, err := s.credential(context.Background())
+			if err != nil {
+				t.Fatalf("credential() = %v", err)
+			}
+			if got.Password != tt.want {
+				t.Errorf("credential() = %v, want %v", got.Password, tt.want)
+			}
+		})
+	}
+}
+
+// 画質が0から100でなければエラーになるか確認。
+func TestLoadConfigScreenShotQuality(t *testing.T) {
+	body := `
+SiteSessionCookieName: session_state
+SiteTopUrl: https://www.dlsite.com/
+LogInUrl: https://login.dlsite.com/login
+LogOutUrl: https://www.dlsite.com/home/logout
+LoginUsernameSel: "#form_id"
+LoginPasswordSel: "#form_password"
+LoginButtonSel: button
+AgePermissionUrl: https://www.dlsite.com/maniax/
+AgePermissionSel: .btn_yes a
+AgePermissionNextSel: "#top_header"
+`
+	tests := []struct {
+		name    string
+		env     string
+		wantErr bool
+	}{
+		{name: "default", env: ""},
+		{name: "100", env: "100"},
+		{name: "101", env: "101", wantErr: true},
+		{name: "negative", env: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			t.Setenv("SCREENSHOT_QUALITY", tt.env)
+			_, err := LoadConfig(writeConfig(t, "dlsite.yaml", body))
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("LoadConfig() = %v", err)
+				}
+				return
+			}
+			var cerr *ConfigError
+			if !errors.As(err, &cerr) || len(cerr.Invalid) != 1 {
+				t.Errorf("LoadConfig() error = %v, want *ConfigError", err)
+			}
+		})
+	}
+}
+
+// 足りない設定が全て報告されるか確認。
+func TestLoadConfigMissing(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := writeConfig(t, "dlsite.json", `{
+	"SiteSessionCookieName": "session_state",
+	"SiteTopUrl": "https://www.dlsite.com/",
+	"LogInUrl": "https://login.dlsite.com/login",
+	"LogOutUrl": "https://www.dlsite.com/home/logout",
+	"LoginUsernameSel": "#form_id",
+	"LoginPasswordSel": "#form_password",
+	"AgePermissionUrl": "https://www.dlsite.com/maniax/"
+}`)
+
+	_, err := LoadConfig(path)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("LoadConfig() = %v, want *ConfigError", err)
+	}
+	want := []string{"LoginButtonSel", "AgePermissionSel", "AgePermissionNextSel"}
+	if !reflect.DeepEqual(configErr.Missing, want) {
+		t.Errorf("LoadConfig() Missing = %v, want %v", configErr.Missing, want)
+	}
+}
+
+// RetryPolicyとFailureCaptureの時間も、DefaultTimeSpanと同じ書き方ができるか確認。
+func TestLoadConfigRetryPolicy(t *testing.T) {
+	clearConfigEnv(t)
+
+	const base = `
+SiteSessionCookieName: session_state
+SiteTopUrl: https://www.dlsite.com/
+LogInUrl: https://login.dlsite.com/login
+LogOutUrl: https://www.dlsite.com/home/logout
+LoginUsernameSel: "#form_id"
+LoginPasswordSel: "#form_password"
+LoginButtonSel: button
+AgePermissionUrl: https://www.dlsite.com/maniax/
+AgePermissionSel: .btn_yes a
+AgePermissionNextSel: "#top_header"
+`
+	tests := []struct {
+		name        string
+		body        string
+		env         string // RETRY_MAX_ATTEMPTS
+		wantPolicy  *RetryPolicy
+		wantTimeout time.Duration
+		wantErr     bool
+	}{
+		{
+			name: "duration",
+			body: base + `
+RetryPolicy:
+  MaxAttempts: 3
+  Backoff: 2s
+  MaxBackoff: 1m
+  Jitter: 0.2
+  Reload: true
+FailureCapture:
+  MaxConsole: 50
+  Timeout: 500ms
+`,
+			wantPolicy:  &RetryPolicy{MaxAttempts: 3, Backoff: 2 * time.Second, MaxBackoff: time.Minute, Jitter: 0.2, Reload: true},
+			wantTimeout: 500 * time.Millisecond,
+		},
+		{
+			name: "seconds",
+			body: base + `
+RetryPolicy:
+  MaxAttempts: 2
+  Backoff: 1
+FailureCapture:
+  Timeout: 5
+`,
+			wantPolicy:  &RetryPolicy{MaxAttempts: 2, Backoff: time.Second},
+			wantTimeout: 5 * time.Second,
+		},
+		{name: "none", body: base},
+		{
+			name: "env keeps backoff",
+			body: base + `
+RetryPolicy:
+  MaxAttempts: 2
+  Backoff: 2s
+  Reload: true
+`,
+			env:        "5",
+			wantPolicy: &RetryPolicy{MaxAttempts: 5, Backoff: 2 * time.Second, Reload: true},
+		},
+		{name: "env only", body: base, env: "3", wantPolicy: &RetryPolicy{MaxAttempts: 3, Jitter: 0.2}},
+		{
+			name: "jitter too large",
+			body: base + `
+RetryPolicy:
+  MaxAttempts: 2
+  Jitter: 1.5
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RETRY_MAX_ATTEMPTS", tt.env)
+			s, err := LoadConfig(writeConfig(t, "dlsite.yaml", tt.body))
+			if tt.wantErr {
+				var configErr *ConfigError
+				if !errors.As(err, &configErr) || len(configErr.Invalid) != 1 {
+					t.Errorf("LoadConfig() = %v, want *ConfigError with Invalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() = %v", err)
+			}
+			if !reflect.DeepEqual(s.RetryPolicy, tt.wantPolicy) {
+				t.Errorf("LoadConfig() RetryPolicy = %+v, want %+v", s.RetryPolicy, tt.wantPolicy)
+			}
+			if tt.wantTimeout == 0 {
+				if s.FailureCapture != nil {
+					t.Errorf("LoadConfig() FailureCapture = %+v, want nil", s.FailureCapture)
+				}
+				return
+			}
+			if s.FailureCapture == nil || s.FailureCapture.Timeout != tt.wantTimeout {
+				t.Errorf("LoadConfig() FailureCapture = %+v, want Timeout %v", s.FailureCapture, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "seconds", args: "2", want: 2 * time.Second},
+		{name: "duration", args: "1m30s", want: 90 * time.Second},
+		{name: "invalid", args: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDuration(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
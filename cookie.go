@@ -0,0 +1,149 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// 保存したセッションの有効期限が切れているときのエラーです。
+// ログインし直すこと。
+var ErrSessionExpired = errors.New("保存したセッションの有効期限が切れています。")
+
+// cookieのファイルの中身です。暗号化して保存する。
+type cookieFile struct {
+	SavedAt time.Time
+	Cookies []*network.Cookie
+}
+
+// cookieを取得するurl。サイトのurlが空なら使わない。
+func (s ScrapingTaskManager) cookieUrls() []string {
+	var urls []string
+	for _, u := range []string{s.SiteTopUrl, s.LogInUrl, s.AgePermissionUrl} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// ブラウザのcookieをCookieSecretで暗号化してファイルに保存する。
+// 次の実行でLoadCookiesTasksで読み込むと、ログインし直さなくて良い。
+func (s ScrapingTaskManager) SaveCookiesTasks(path string) chromedp.Tasks {
+	return chromedp.Tasks{
+		s.stepAction(step{Task: "SaveCookies", Args: []string{path}, Failed: "cookieを保存できませんでした。"}, func(ctx context.Context) error {
+			cookies, err := network.GetCookies().WithUrls(s.cookieUrls()).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("cookieが取得できませんでした。: %w", err)
+			}
+
+			b, err := json.Marshal(cookieFile{SavedAt: time.Now(), Cookies: cookies})
+			if err != nil {
+				return err
+			}
+			b, err = encrypt(s.CookieSecret, b)
+			if err != nil {
+				return fmt.Errorf("cookieを暗号化できませんでした。: %w", err)
+			}
+			err = os.WriteFile(path, b, 0600)
+			if err != nil {
+				return fmt.Errorf("cookieをファイルに保存できませんでした。: %w", err)
+			}
+			s.logger().Info("cookieを保存しました。", "count", len(cookies), "path", path)
+			return nil
+		}),
+	}
+}
+
+// SaveCookiesTasksで保存したcookieをブラウザに読み込む。
+// セッションのcookieが無いか有効期限が切れている場合は、何も読み込まずにErrSessionExpiredを返す。
+// CookieMaxAgeを設定した場合は、保存してからそれ以上経っていても期限切れとみなす。
+// ファイルが無ければfs.ErrNotExistになるエラーを返す。
+// 初めて実行したときなど失敗とは限らないので、この2つはErrorでログに出さない。どう扱うかは呼び出し側で決めること。
+func (s ScrapingTaskManager) LoadCookiesTasks(path string) chromedp.Tasks {
+	st := step{
+		Task:     "LoadCookies",
+		Args:     []string{path},
+		Failed:   "cookieを読み込めませんでした。",
+		Expected: []error{fs.ErrNotExist, ErrSessionExpired},
+	}
+	return chromedp.Tasks{
+		s.stepAction(st, func(ctx context.Context) error {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("cookieのファイルを読み込めませんでした。: %w", err)
+			}
+			b, err = decrypt(s.CookieSecret, b)
+			if err != nil {
+				return fmt.Errorf("cookieを復号できませんでした。: %w", err)
+			}
+			var saved cookieFile
+			if err := json.Unmarshal(b, &saved); err != nil {
+				return fmt.Errorf("cookieのファイルを解釈できませんでした。: %w", err)
+			}
+
+			params, err := s.liveCookies(saved, time.Now())
+			if err != nil {
+				return err
+			}
+			err = network.SetCookies(params).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("cookieを設定できませんでした。: %w", err)
+			}
+			s.logger().Info("cookieを読み込みました。", "count", len(params), "path", path)
+			return nil
+		}),
+	}
+}
+
+// 保存したcookieから有効期限が切れていないものを選んで、設定できる形にする。
+// セッションのcookieが残っていなければErrSessionExpired。
+func (s ScrapingTaskManager) liveCookies(saved cookieFile, now time.Time) ([]*network.CookieParam, error) {
+	if s.CookieMaxAge > 0 && now.Sub(saved.SavedAt) > s.CookieMaxAge {
+		return nil, ErrSessionExpired
+	}
+
+	var params []*network.CookieParam
+	session := false
+	for _, c := range saved.Cookies {
+		param := &network.CookieParam{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			Secure:       c.Secure,
+			HTTPOnly:     c.HTTPOnly,
+			SameSite:     c.SameSite,
+			Priority:     c.Priority,
+			SameParty:    c.SameParty,
+			SourceScheme: c.SourceScheme,
+			SourcePort:   c.SourcePort,
+			PartitionKey: c.PartitionKey,
+		}
+		if !c.Session && c.Expires > 0 {
+			expires := time.Unix(0, int64(c.Expires*float64(time.Second)))
+			if !expires.After(now) {
+				continue
+			}
+			t := cdp.TimeSinceEpoch(expires)
+			param.Expires = &t
+		}
+		if c.Name == s.SiteSessionCookieName {
+			session = true
+		}
+		params = append(params, param)
+	}
+
+	if !session {
+		return nil, ErrSessionExpired
+	}
+	return params, nil
+}
@@ -0,0 +1,128 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// 暗号化したものが同じ鍵で復号でき、違う鍵では復号できないか確認。
+func TestEncrypt(t *testing.T) {
+	plain := []byte(`{"Cookies":[]}`)
+	data, err := encrypt("secret", plain)
+	if err != nil {
+		t.Fatalf("encrypt() = %v", err)
+	}
+
+	got, err := decrypt("secret", data)
+	if err != nil || string(got) != string(plain) {
+		t.Errorf("decrypt() = %s, %v, want %s", got, err, plain)
+	}
+	if _, err := decrypt("wrong", data); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("decrypt() = %v, want %v", err, ErrDecrypt)
+	}
+}
+
+// 有効期限が切れたcookieが除かれ、セッションが無ければ期限切れになるか確認。
+func TestLiveCookies(t *testing.T) {
+	now := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := ScrapingTaskManager{SiteSessionCookieName: "session_state"}
+	unix := func(t time.Time) float64 {
+		return float64(t.Unix())
+	}
+
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		saved   cookieFile
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "live",
+			saved: cookieFile{
+				SavedAt: now.Add(-time.Hour),
+				Cookies: []*network.Cookie{
+					{Name: "session_state", Expires: unix(now.Add(time.Hour))},
+					{Name: "adultchecked", Session: true, Expires: -1},
+					{Name: "old", Expires: unix(now.Add(-time.Minute))},
+				},
+			},
+			want: []string{"session_state", "adultchecked"},
+		},
+		{
+			name: "expired",
+			saved: cookieFile{
+				SavedAt: now.Add(-time.Hour),
+				Cookies: []*network.Cookie{
+					{Name: "session_state", Expires: unix(now.Add(-time.Minute))},
+				},
+			},
+			wantErr: ErrSessionExpired,
+		},
+		{
+			name:   "max age",
+			maxAge: 30 * time.Minute,
+			saved: cookieFile{
+				SavedAt: now.Add(-time.Hour),
+				Cookies: []*network.Cookie{
+					{Name: "session_state", Session: true, Expires: -1},
+				},
+			},
+			wantErr: ErrSessionExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.CookieMaxAge = tt.maxAge
+			params, err := s.liveCookies(tt.saved, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("liveCookies() = %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, p := range params {
+				got = append(got, p.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("liveCookies() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("liveCookies() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// cookieのファイルが無いときは、Errorでログに出さずに、呼び出し側でわかるエラーを返すことの確認。
+func TestLoadCookiesTasksNotExist(t *testing.T) {
+	var buf bytes.Buffer
+	dir := t.TempDir()
+	s := ScrapingTaskManager{
+		Logger:         slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		FailureCapture: &FailureCapture{Dir: dir},
+	}
+	err := s.LoadCookiesTasks(filepath.Join(dir, "cookies")).Do(context.Background())
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadCookiesTasks() error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if strings.Contains(buf.String(), `"level":"ERROR"`) {
+		t.Errorf("LoadCookiesTasks() Errorでログに出ています。: %s", buf.String())
+	}
+	logs, err := filepath.Glob(filepath.Join(dir, "*_failure.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("保存されたログ = %v, want 0件", logs)
+	}
+}
@@ -0,0 +1,219 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// 伏せた値の代わりに表示する文字列。
+const redactedText = "[REDACTED]"
+
+// ログインに使うユーザー名とパスワードです。
+type Credential struct {
+	Username string
+	Password string
+}
+
+func (c Credential) String() string {
+	return fmt.Sprintf("{Username:%s Password:%s}", c.Username, redact(c.Password))
+}
+
+func (c Credential) GoString() string {
+	return fmt.Sprintf("tasks.Credential{Username:%q, Password:%q}", c.Username, redact(c.Password))
+}
+
+// ログインに使うユーザー名とパスワードを返します。
+// ScrapingTaskManagerのCredentialsに設定すると、LoginUsername, LoginPasswordの代わりに使われる。
+// Usernameが空なら、LoginUsernameが使われる。
+type CredentialProvider interface {
+	Credential(ctx context.Context) (Credential, error)
+}
+
+// 環境変数から読むCredentialProviderです。
+// 空ならLOGIN_USERNAME, LOGIN_PASSWORDから読む。
+type EnvCredentials struct {
+	UsernameEnv string
+	PasswordEnv string
+}
+
+func (e EnvCredentials) Credential(ctx context.Context) (Credential, error) {
+	usernameEnv, passwordEnv := e.UsernameEnv, e.PasswordEnv
+	if usernameEnv == "" {
+		usernameEnv = "LOGIN_USERNAME"
+	}
+	if passwordEnv == "" {
+		passwordEnv = "LOGIN_PASSWORD"
+	}
+	password := os.Getenv(passwordEnv)
+	if password == "" {
+		return Credential{}, fmt.Errorf("環境変数%sにパスワードがありません。", passwordEnv)
+	}
+	return Credential{Username: os.Getenv(usernameEnv), Password: password}, nil
+}
+
+// ファイルからパスワードを読むCredentialProviderです。
+// ファイルの中身の前後の空白を除いたものをパスワードにする。
+// 持ち主以外が読み書きできるファイルは使わない。chmod 600にすること。
+type FileCredentials struct {
+	Path     string
+	Username string
+}
+
+func (f FileCredentials) Credential(ctx context.Context) (Credential, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("パスワードのファイルを読み込めませんでした。: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return Credential{}, fmt.Errorf("パスワードのファイルが持ち主以外からも読み書きできます。chmod 600にしてください。: %s %s", f.Path, info.Mode().Perm())
+	}
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("パスワードのファイルを読み込めませんでした。: %w", err)
+	}
+	password := strings.TrimSpace(string(b))
+	if password == "" {
+		return Credential{}, fmt.Errorf("パスワードのファイルが空です。: %s", f.Path)
+	}
+	return Credential{Username: f.Username, Password: password}, nil
+}
+
+// コマンドの出力の1行目をパスワードにするCredentialProviderです。
+// pass show dlsite や secret-tool lookup service dlsite のようなパスワード管理のコマンドを使う。
+type CommandCredentials struct {
+	Command  []string
+	Username string
+}
+
+func (c CommandCredentials) Credential(ctx context.Context) (Credential, error) {
+	if len(c.Command) == 0 {
+		return Credential{}, errors.New("パスワードを取得するコマンドが設定されていません。")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Credential{}, fmt.Errorf("パスワードを取得するコマンドが失敗しました。: %w %s", err, strings.TrimSpace(stderr.String()))
+	}
+	password, _, _ := strings.Cut(string(out), "\n")
+	password = strings.TrimSpace(password)
+	if password == "" {
+		return Credential{}, fmt.Errorf("パスワードを取得するコマンドが何も出力しませんでした。: %s", c.Command[0])
+	}
+	return Credential{Username: c.Username, Password: password}, nil
+}
+
+// Secretで暗号化したファイルから読むCredentialProviderです。
+// ファイルはSaveKeystoreで作る。暗号化はcookieの保存と同じ。
+type KeystoreCredentials struct {
+	Path   string
+	Secret string
+}
+
+func (k KeystoreCredentials) Credential(ctx context.Context) (Credential, error) {
+	b, err := os.ReadFile(k.Path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("キーストアを読み込めませんでした。: %w", err)
+	}
+	b, err = decrypt(k.Secret, b)
+	if err != nil {
+		return Credential{}, err
+	}
+	var c Credential
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Credential{}, fmt.Errorf("キーストアを解釈できませんでした。: %w", err)
+	}
+	return c, nil
+}
+
+func (k KeystoreCredentials) String() string {
+	return fmt.Sprintf("{Path:%s Secret:%s}", k.Path, redact(k.Secret))
+}
+
+func (k KeystoreCredentials) GoString() string {
+	return fmt.Sprintf("tasks.KeystoreCredentials{Path:%q, Secret:%q}", k.Path, redact(k.Secret))
+}
+
+// ユーザー名とパスワードをsecretで暗号化して保存する。KeystoreCredentialsで読み込める。
+func SaveKeystore(path string, secret string, c Credential) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	b, err = encrypt(secret, b)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("キーストアを保存できませんでした。: %w", err)
+	}
+	return nil
+}
+
+// ログインに使うユーザー名とパスワード。
+// Credentialsがあればそちらを使う。
+func (s ScrapingTaskManager) credential(ctx context.Context) (Credential, error) {
+	if s.Credentials == nil {
+		return Credential{Username: s.LoginUsername, Password: s.LoginPassword}, nil
+	}
+	c, err := s.Credentials.Credential(ctx)
+	if err != nil {
+		return Credential{}, err
+	}
+	if c.Username == "" {
+		c.Username = s.LoginUsername
+	}
+	return c, nil
+}
+
+// 秘密の値を伏せる。空なら空のまま。
+func redact(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redactedText
+}
+
+// 秘密の値を伏せて表示するための型です。メソッドを持たないのでString()が再帰しない。
+type redactedManager ScrapingTaskManager
+
+func (s ScrapingTaskManager) redacted() redactedManager {
+	s.LoginPassword = redact(s.LoginPassword)
+	s.CookieSecret = redact(s.CookieSecret)
+	if s.OTPProvider != nil {
+		s.OTPProvider = redactedOTP{}
+	}
+	return redactedManager(s)
+}
+
+// パスワードなどの秘密の値を伏せて表示する。
+func (s ScrapingTaskManager) String() string {
+	return fmt.Sprintf("%+v", s.redacted())
+}
+
+// %#vでもパスワードなどの秘密の値を伏せて表示する。
+func (s ScrapingTaskManager) GoString() string {
+	return strings.Replace(fmt.Sprintf("%#v", s.redacted()), "tasks.redactedManager", "tasks.ScrapingTaskManager", 1)
+}
+
+// 2段階認証の秘密鍵を表示しないために、表示するときだけOTPProviderを置き換える。
+type redactedOTP struct{}
+
+func (redactedOTP) OTP(ctx context.Context) (string, error) {
+	return "", errors.New("表示用のOTPProviderは使えません。")
+}
+
+func (redactedOTP) String() string {
+	return redactedText
+}
+
+func (redactedOTP) GoString() string {
+	return redactedText
+}
@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 表示したときに秘密の値が含まれないか確認。
+func TestScrapingTaskManagerString(t *testing.T) {
+	s := ScrapingTaskManager{
+		LoginUsername: "user",
+		LoginPassword: "password-value",
+		CookieSecret:  "cookie-secret-value",
+		OTPProvider:   TOTP{Secret: "GEZDGNBVGY3TQOJQ"},
+		Credentials:   KeystoreCredentials{Path: "keystore", Secret: "keystore-secret-value"},
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		got := fmt.Sprintf(format, s)
+		for _, secret := range []string{"password-value", "cookie-secret-value", "GEZDGNBVGY3TQOJQ", "keystore-secret-value"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("fmt.Sprintf(%q) に %s が含まれています。: %s", format, secret, got)
+			}
+		}
+		if !strings.Contains(got, "user") {
+			t.Errorf("fmt.Sprintf(%q) にユーザー名が含まれていません。: %s", format, got)
+		}
+	}
+}
+
+// 持ち主以外が読めるファイルを使わないか確認。
+func TestFileCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		perm    os.FileMode
+		want    string
+		wantErr bool
+	}{
+		{name: "private", perm: 0600, want: "password"},
+		{name: "public", perm: 0644, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "password")
+			if err := os.WriteFile(path, []byte("password\n"), tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			got, err := FileCredentials{Path: path}.Credential(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FileCredentials.Credential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Password != tt.want {
+				t.Errorf("FileCredentials.Credential() = %v, want %v", got.Password, tt.want)
+			}
+		})
+	}
+}
+
+// 保存したキーストアを同じ鍵で読めて、違う鍵では読めないか確認。
+func TestKeystoreCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore")
+	want := Credential{Username: "user", Password: "password"}
+	if err := SaveKeystore(path, "secret", want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := KeystoreCredentials{Path: path, Secret: "secret"}.Credential(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("KeystoreCredentials.Credential() = %#v, want %#v", got, want)
+	}
+
+	if _, err := (KeystoreCredentials{Path: path, Secret: "wrong"}).Credential(context.Background()); err != ErrDecrypt {
+		t.Errorf("KeystoreCredentials.Credential() error = %v, want %v", err, ErrDecrypt)
+	}
+}
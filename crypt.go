@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 暗号化したファイルの先頭につける印。
+var cryptMagic = []byte("DLSC1")
+
+const cryptSaltSize = 16
+
+// 鍵が間違っているか、ファイルが壊れているときのエラーです。
+var ErrDecrypt = errors.New("復号できませんでした。鍵が間違っているか、ファイルが壊れています。")
+
+// secretから作った鍵で暗号化する。
+// 形式は 印 + salt + nonce + AES-GCMで暗号化したもの。
+func encrypt(secret string, plain []byte) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("暗号化の鍵が設定されていません。")
+	}
+	salt := make([]byte, cryptSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, cryptMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plain, cryptMagic), nil
+}
+
+// encryptで暗号化したものを復号する。
+func decrypt(secret string, data []byte) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("暗号化の鍵が設定されていません。")
+	}
+	if !bytes.HasPrefix(data, cryptMagic) || len(data) < len(cryptMagic)+cryptSaltSize {
+		return nil, ErrDecrypt
+	}
+	data = data[len(cryptMagic):]
+	salt, data := data[:cryptSaltSize], data[cryptSaltSize:]
+
+	aead, err := newAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, cryptMagic)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+func newAEAD(secret string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(secret), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("鍵を作れませんでした。: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
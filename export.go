@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// csvに書き出せる値です。
+type Record interface {
+	CSVHeader() []string
+	CSVRecord() []string
+}
+
+// 一覧をcsvかjsonで書き出す。
+// format csvかjson
+func Export[T Record](w io.Writer, format string, items []T) error {
+	if err := checkExportFormat(format); err != nil {
+		return err
+	}
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		var zero T
+		if err := cw.Write(zero.CSVHeader()); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := cw.Write(item.CSVRecord()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	}
+	return nil
+}
+
+// 対応している形式か確認する。
+func checkExportFormat(format string) error {
+	switch format {
+	case "csv", "json":
+		return nil
+	}
+	return fmt.Errorf("対応していない形式です。: %s", format)
+}
+
+// 一覧をファイルに書き出す。形式は拡張子で判断する。.csvか.jsonに対応。
+// 対応していない拡張子なら、ファイルを開かずにエラーを返す。
+func ExportFile[T Record](path string, items []T) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if err := checkExportFormat(format); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return fmt.Errorf("書き出し先のファイルを開けませんでした。: %w", err)
+	}
+	defer file.Close()
+
+	if err := Export(file, format, items); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,238 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// 処理が失敗したときに、そのときのページの状態を保存する設定です。
+// ScrapingTaskManagerのFailureCaptureに設定すると、どの処理が失敗しても
+// ページ全体のスクリーンショット、url、html、コンソールのログを保存してからエラーを返す。
+type FailureCapture struct {
+	Dir        string        // 保存先。空ならScreenShotLogPath。
+	MaxConsole int           // 残すコンソールのログの行数。0なら200。
+	Timeout    time.Duration // 保存にかける時間の上限。0なら10秒。
+
+	mu        sync.Mutex
+	console   []string
+	listening map[target.ID]bool
+}
+
+// 保存したファイルです。
+type failureFiles struct {
+	Screenshot string
+	Html       string
+	Log        string
+}
+
+// 処理が失敗したかを、一番外側の処理と中の処理で共有するキー。
+// 中の処理で保存したら外側では保存しない。
+type failureCapturedKey struct{}
+
+// やり直す処理の中で失敗したときに、保存を後回しにするためのキー。
+// やり直して成功したら保存しない。
+type deferredCaptureKey struct{}
+
+// 後回しにした保存です。最初に失敗した処理の分だけ持つ。
+type deferredCapture struct {
+	save func()
+}
+
+// やり直す処理の1回分のcontext。中で失敗しても、finishを呼ぶまで保存しない。
+func deferCapture(ctx context.Context) (context.Context, *deferredCapture) {
+	d := &deferredCapture{}
+	return context.WithValue(ctx, deferredCaptureKey{}, d), d
+}
+
+// やり直さずに失敗を返すときに呼ぶ。外側でもやり直す場合は、外側に任せる。
+// ctx deferCaptureに渡したcontext。
+func (d *deferredCapture) finish(ctx context.Context) {
+	if d.save == nil {
+		return
+	}
+	if outer, ok := ctx.Value(deferredCaptureKey{}).(*deferredCapture); ok {
+		if outer.save == nil {
+			outer.save = d.save
+		}
+		return
+	}
+	d.save()
+}
+
+// 失敗したときのページの状態を、まだ保存していなければ保存する。
+// やり直す処理の中なら、やり直さないと決まるまで後回しにする。
+func (s ScrapingTaskManager) saveFailure(ctx context.Context, st step, cause error) {
+	captured := ctx.Value(failureCapturedKey{}).(*bool)
+	if *captured {
+		return
+	}
+	save := func() {
+		*captured = true
+		files, err := s.captureFailure(ctx, st, cause)
+		if err != nil {
+			s.logger().Warn(err.Error(), "task", st.Task)
+		}
+		s.logger().Info("失敗したときのページの状態を保存しました。",
+			"task", st.Task, "screenshot", files.Screenshot, "html", files.Html, "log", files.Log)
+	}
+	if d, ok := ctx.Value(deferredCaptureKey{}).(*deferredCapture); ok {
+		if d.save == nil {
+			d.save = save
+		}
+		return
+	}
+	save()
+}
+
+// コンソールのログを集め始める。タブごとに1回だけ登録する。
+// ログは失敗する前から集めておく必要があるので、処理を始めるたびに呼ぶ。
+func (f *FailureCapture) listen(ctx context.Context) {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil {
+		return
+	}
+	if !f.startListening(ctx, c.Target.TargetID) {
+		return
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			var args []string
+			for _, arg := range ev.Args {
+				if arg.Value != nil {
+					args = append(args, string(arg.Value))
+				} else {
+					args = append(args, arg.Description)
+				}
+			}
+			f.addConsole(fmt.Sprintf("console.%s: %s", ev.Type, strings.Join(args, " ")))
+		case *runtime.EventExceptionThrown:
+			text := ev.ExceptionDetails.Text
+			if ev.ExceptionDetails.Exception != nil {
+				text += " " + ev.ExceptionDetails.Exception.Description
+			}
+			f.addConsole("exception: " + text)
+		case *cdplog.EventEntryAdded:
+			f.addConsole(fmt.Sprintf("%s %s: %s %s", ev.Entry.Source, ev.Entry.Level, ev.Entry.Text, ev.Entry.URL))
+		}
+	})
+}
+
+// タブでまだ集めていなければ、集めていることにしてtrueを返す。
+// 登録したctxが終わるとListenTargetの登録も消えるので、そのときに次の処理で登録し直せるようにする。
+func (f *FailureCapture) startListening(ctx context.Context, id target.ID) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listening == nil {
+		f.listening = map[target.ID]bool{}
+	}
+	if f.listening[id] {
+		return false
+	}
+	f.listening[id] = true
+	context.AfterFunc(ctx, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.listening, id)
+	})
+	return true
+}
+
+func (f *FailureCapture) addConsole(line string) {
+	max := f.MaxConsole
+	if max == 0 {
+		max = 200
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.console = append(f.console, time.Now().Format("15:04:05.000")+" "+line)
+	if len(f.console) > max {
+		f.console = f.console[len(f.console)-max:]
+	}
+}
+
+// 失敗したときのページの状態を保存する。
+// 処理のcontextはタイムアウトしていることがあるので、別に時間を区切って保存する。
+func (s ScrapingTaskManager) captureFailure(ctx context.Context, st step, cause error) (failureFiles, error) {
+	f := s.FailureCapture
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	dir := f.Dir
+	if dir == "" {
+		dir = s.ScreenShotLogPath
+	}
+	base := filepath.Join(dir, time.Now().Format(s.ScreenShotLogPrefix)+st.Task+"_failure")
+	files := failureFiles{Screenshot: base + ".png", Html: base + ".html", Log: base + ".log"}
+
+	var href, html string
+	// 取れたものだけでも保存したいので、エラーは最後にまとめて返す。
+	var errs []string
+	if err := chromedp.Evaluate("window.location.href", &href).Do(ctx); err != nil {
+		errs = append(errs, "url: "+err.Error())
+	}
+	if err := chromedp.Evaluate("document.documentElement.outerHTML", &html).Do(ctx); err != nil {
+		errs = append(errs, "html: "+err.Error())
+	}
+	image, err := s.screenshot(ctx, nil, "png")
+	if err != nil {
+		errs = append(errs, "screenshot: "+err.Error())
+	}
+
+	f.mu.Lock()
+	console := append([]string(nil), f.console...)
+	f.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "task: %s\n", st.Task)
+	if st.Sel != nil {
+		fmt.Fprintf(&b, "selector: %v\n", st.Sel)
+	}
+	if st.Url != "" {
+		fmt.Fprintf(&b, "url: %s\n", st.Url)
+	}
+	fmt.Fprintf(&b, "error: %v\n", cause)
+	fmt.Fprintf(&b, "href: %s\n", href)
+	fmt.Fprintf(&b, "\nconsole:\n%s\n", strings.Join(console, "\n"))
+
+	if err := os.WriteFile(files.Log, []byte(b.String()), 0640); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if html != "" {
+		if err := os.WriteFile(files.Html, []byte(html), 0640); err != nil {
+			errs = append(errs, err.Error())
+		}
+	} else {
+		files.Html = ""
+	}
+	if image != nil {
+		if err := os.WriteFile(files.Screenshot, image, 0640); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if s.Trace != nil {
+			s.Trace.addScreenshot(ctx, "png", image)
+		}
+	} else {
+		files.Screenshot = ""
+	}
+
+	if len(errs) > 0 {
+		return files, fmt.Errorf("失敗したときのページの状態を一部保存できませんでした。: %s", strings.Join(errs, ", "))
+	}
+	return files, nil
+}
@@ -0,0 +1,146 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 失敗したときに、中の処理の分だけページの状態が保存されるか確認。
+// ブラウザが無いのでスクリーンショットとhtmlは取れず、ログだけが保存される。
+func TestFailureCapture(t *testing.T) {
+	dir := t.TempDir()
+	s := ScrapingTaskManager{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		FailureCapture: &FailureCapture{Dir: dir},
+	}
+
+	want := errors.New("クリックできません")
+	err := s.stepAction(step{Task: "Login"}, func(ctx context.Context) error {
+		return s.stepAction(step{Task: "Click", Sel: "#button"}, func(ctx context.Context) error {
+			return want
+		}).Do(ctx)
+	}).Do(context.Background())
+	if err != want {
+		t.Errorf("stepAction() error = %v, want %v", err, want)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(dir, "*_failure.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("保存されたログ = %v, want 1件", logs)
+	}
+	if !strings.HasSuffix(logs[0], "Click_failure.log") {
+		t.Errorf("保存されたログ = %v, want Click_failure.log", logs[0])
+	}
+	b, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"task: Click", "selector: #button", "error: クリックできません"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("保存されたログに%sがありません。: %s", s, b)
+		}
+	}
+}
+
+// 登録したcontextが終わったら、次の処理で登録し直せることの確認。
+func TestFailureCaptureStartListening(t *testing.T) {
+	f := &FailureCapture{}
+	ctx, cancel := context.WithCancel(context.Background())
+	if !f.startListening(ctx, "tab") {
+		t.Fatal("startListening() = false, want true")
+	}
+	if f.startListening(context.Background(), "tab") {
+		t.Error("startListening() = true, want false while listening")
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for !f.startListening(context.Background(), "tab") {
+		if time.Now().After(deadline) {
+			t.Fatal("startListening() = false after the context ended, want true")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// やり直す処理では、最後に失敗したときだけ保存されるか確認。
+func TestFailureCaptureRetry(t *testing.T) {
+	fail := errors.New("クリックできません")
+	tests := []struct {
+		name     string
+		failures int // 何回目まで失敗するか
+		outer    bool
+		wantErr  bool
+		wantLogs int
+	}{
+		{name: "succeeded on retry", failures: 1, wantLogs: 0},
+		{name: "failed every attempt", failures: 2, wantErr: true, wantLogs: 1},
+		// 中でやり直しきれなくても、外側でやり直して成功したら保存しない。
+		{name: "succeeded on outer retry", failures: 2, outer: true, wantLogs: 0},
+		{name: "failed every outer attempt", failures: 4, outer: true, wantErr: true, wantLogs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := ScrapingTaskManager{
+				Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+				FailureCapture: &FailureCapture{Dir: dir},
+				RetryPolicy:    &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+			}
+			calls := 0
+			var action chromedp.Action = s.RetryTasks(nil, s.stepAction(step{Task: "Click", Sel: "#button"}, func(ctx context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return fail
+				}
+				return nil
+			}))
+			if tt.outer {
+				action = s.RetryTasks(nil, action)
+			}
+			err := action.Do(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RetryTasks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			logs, err := filepath.Glob(filepath.Join(dir, "*_failure.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != tt.wantLogs {
+				t.Errorf("保存されたログ = %v, want %d件", logs, tt.wantLogs)
+			}
+		})
+	}
+}
+
+// 失敗しても止めない処理では保存されないことの確認。
+func TestFailureCaptureNoCapture(t *testing.T) {
+	dir := t.TempDir()
+	s := ScrapingTaskManager{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		FailureCapture: &FailureCapture{Dir: dir},
+	}
+	s.stepAction(step{Task: "SendKeys", Sel: "#input", NoCapture: true}, func(ctx context.Context) error {
+		return errors.New("キー入力ができません")
+	}).Do(context.Background())
+
+	logs, err := filepath.Glob(filepath.Join(dir, "*_failure.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("保存されたログ = %v, want 0件", logs)
+	}
+}
@@ -1,15 +1,22 @@
 module github.com/KatsutoshiOtogawa/dlsite_scraping_go
 
-go 1.20
+go 1.21
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/chromedp/cdproto v0.0.0-20230625224106-7fafe342e117
+	github.com/chromedp/chromedp v0.9.1
+	go.etcd.io/bbolt v1.3.8
+	golang.org/x/crypto v0.11.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/chromedp/cdproto v0.0.0-20230625224106-7fafe342e117 // indirect
-	github.com/chromedp/chromedp v0.9.1 // indirect
 	github.com/chromedp/sysutil v1.0.0 // indirect
 	github.com/gobwas/httphead v0.1.0 // indirect
 	github.com/gobwas/pool v0.2.1 // indirect
 	github.com/gobwas/ws v1.2.1 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
-	golang.org/x/sys v0.9.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 )
@@ -0,0 +1,126 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 一覧ページの1件分です。キーはフィールド名。
+type listItem map[string]listValue
+
+// 一覧ページの1件の中の要素から取得した値です。
+type listValue struct {
+	Text  string
+	Href  string
+	Class string
+	Src   string // data-srcかsrc
+}
+
+// 一覧ページの値をまとめて取得するjavascript。withSelectorScriptで実行する。
+// 引数は1件分の要素のセレクタと、1件の中で取得する要素のセレクタ。
+// セレクタが空文字なら1件分の要素そのものを使う。XPathで1件の中を探す場合は.//で始めること。
+const listScript = `(function(item, fields) {
+	return findAll(item).map((e) => {
+		const r = {};
+		for (const [name, sel] of Object.entries(fields)) {
+			const f = sel ? find(sel, e) : e;
+			if (!f) {
+				continue;
+			}
+			r[name] = {
+				Text: f.textContent.replace(/\s+/g, " ").trim(),
+				Href: f.href || "",
+				Class: typeof f.className === "string" ? f.className : "",
+				Src: (f.dataset && f.dataset.src) || f.getAttribute("src") || "",
+			};
+		}
+		return r;
+	});
+})(%q, %s)`
+
+var (
+	productIDPattern = regexp.MustCompile(`product_id/([A-Z]{2}[0-9]+)`)
+	ratingPattern    = regexp.MustCompile(`star_([0-9]+)`)
+)
+
+// 一覧ページの各項目を取得する。
+// itemSel 1件分の要素のセレクタ
+// fields フィールド名と1件の中のセレクタ
+func (s ScrapingTaskManager) listTasks(itemSel Selector, fields map[string]Selector, out *[]listItem) chromedp.Action {
+	return s.stepAction(step{Task: "List", Sel: itemSel, Failed: "一覧を取得できませんでした。"}, func(ctx context.Context) error {
+		// ClickTasksなどと同じく、前に何もつけていないセレクタはdevtoolsの検索と同じように探す。
+		sels := map[string]Selector{}
+		for name, sel := range fields {
+			sels[name] = searchSelector(sel)
+		}
+		b, err := json.Marshal(sels)
+		if err != nil {
+			return err
+		}
+		var items []listItem
+		err = chromedp.Evaluate(withSelectorScript(fmt.Sprintf(listScript, searchSelector(itemSel), b)), &items).Do(ctx)
+		if err != nil {
+			return err
+		}
+		*out = items
+		s.logger().Info("一覧を取得しました。", "count", len(items))
+		return nil
+	})
+}
+
+// 次のページへのリンクを辿りながら、各ページでpageを呼ぶ。
+// 次のページへのリンクが無くなるか、maxPagesに達したら終わる。maxPagesが0以下なら最後のページまで辿る。
+func (s ScrapingTaskManager) paginateTasks(nextSel Selector, maxPages int, waitTime time.Duration, page func(ctx context.Context) error) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for n := 1; ; n++ {
+			if err := page(ctx); err != nil {
+				return err
+			}
+			if maxPages > 0 && n >= maxPages {
+				s.logger().Info("指定したページまで取得しました。", "pages", n)
+				return nil
+			}
+
+			var count int
+			if err := s.CountTasks(nextSel, &count).Do(ctx); err != nil {
+				return err
+			}
+			if count == 0 {
+				s.logger().Info("最後のページまで取得しました。", "pages", n)
+				return nil
+			}
+			if err := s.ClickTasks(nextSel, waitTime).Do(ctx); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// 作品ページのurlから作品IDを取り出す。見つからなければ空文字。
+func ProductIDFromURL(url string) string {
+	m := productIDPattern.FindStringSubmatch(url)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// 評価の星を数値にする。
+// "star_45"のようなクラス名か、"4.50"のような数値を受け付ける。読めなければ0。
+func parseRating(v listValue) float64 {
+	if m := ratingPattern.FindStringSubmatch(v.Class); m != nil {
+		num, _ := strconv.Atoi(m[1])
+		return float64(num) / 10
+	}
+	rating, err := strconv.ParseFloat(v.Text, 64)
+	if err != nil {
+		return 0
+	}
+	return rating
+}
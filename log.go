@@ -0,0 +1,146 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ログに出す処理の1ステップです。
+type step struct {
+	Task   string      // 処理の名前。メソッド名からTasksを除いたもの。
+	Sel    interface{} // 対象のSelector。無ければnil。
+	Url    string      // 移動先のurl。無ければ空。
+	Args   []string    // トレースに残すそのほかの引数。入力した文字列などの秘密になりうるものは入れない。
+	Failed string      // 失敗したときのメッセージ
+
+	NoTimeout bool // TaskTimeoutで区切らない。決まった時間待つ処理など、自分で時間を決めているもの。
+	NoCapture bool // 失敗してもFailureCaptureで保存しない。失敗しても止めない処理。
+
+	// 呼び出し側で扱うエラー。ErrorではなくDebugでログに出し、FailureCaptureで保存しない。
+	// cookieのファイルが無いときなど、失敗とは限らないもの。
+	Expected []error
+}
+
+// errがExpectedのどれかか。
+func (st step) expected(err error) bool {
+	for _, e := range st.Expected {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// 何回目の実行かをcontextに入れるキー。
+type attemptKey struct{}
+
+// 何回目の実行かをcontextに入れる。やり直すときに使う。ログのattemptになる。
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// contextに入れた実行回数。入っていなければ1。
+func attemptFrom(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// ログの出力先。Loggerが無ければslog.Default()。
+func (s ScrapingTaskManager) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
+	}
+	return s.Logger
+}
+
+// fnを1ステップとして実行して、処理の名前、Selector、url、かかった時間、エラー、何回目の実行かをログに出す。
+// Traceがあればトレースにも記録する。FailureCaptureがあれば、失敗したときにページの状態を保存する。
+// TaskTimeoutがあれば、その時間で区切る。タイムアウトしたらTimeoutErrorを返す。
+func (s ScrapingTaskManager) stepAction(st step, fn func(ctx context.Context) error) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		if s.Trace != nil {
+			ts := s.Trace.begin(st, attemptFrom(ctx))
+			ctx = context.WithValue(ctx, traceStepKey{}, ts)
+		}
+		if s.FailureCapture != nil {
+			s.FailureCapture.listen(ctx)
+			if ctx.Value(failureCapturedKey{}) == nil {
+				ctx = context.WithValue(ctx, failureCapturedKey{}, new(bool))
+			}
+		}
+		fctx := ctx
+		if s.TaskTimeout > 0 && !st.NoTimeout {
+			var cancel context.CancelFunc
+			fctx, cancel = context.WithTimeout(ctx, s.TaskTimeout)
+			defer cancel()
+		}
+		err := fn(fctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = timeoutError(ctx, st, time.Since(start), err)
+		}
+		expected := err != nil && st.expected(err)
+		if err != nil && s.FailureCapture != nil && !st.NoCapture && !expected {
+			s.saveFailure(ctx, st, err)
+		}
+		if s.Trace != nil {
+			s.Trace.finish(traceStepFrom(ctx), err)
+		}
+
+		attrs := []slog.Attr{slog.String("task", st.Task)}
+		if st.Sel != nil {
+			attrs = append(attrs, slog.String("selector", fmt.Sprint(st.Sel)))
+		}
+		if st.Url != "" {
+			attrs = append(attrs, slog.String("url", st.Url))
+		}
+		attrs = append(attrs,
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("attempt", attemptFrom(ctx)),
+		)
+		if err != nil {
+			msg := st.Failed
+			if msg == "" {
+				msg = "処理に失敗しました。"
+			}
+			attrs = append(attrs, slog.Any("error", err))
+			level := slog.LevelError
+			if expected {
+				level = slog.LevelDebug
+			}
+			s.logger().LogAttrs(ctx, level, msg, attrs...)
+			return err
+		}
+		s.logger().LogAttrs(ctx, slog.LevelInfo, "処理が終わりました。", attrs...)
+		return nil
+	}
+}
+
+// ログをjsonで1行ずつpathのファイルに追記するLoggerを返す。
+// 使い終わったら返したio.CloserでCloseすること。
+func NewJSONFileLogger(path string, level slog.Leveler) (*slog.Logger, io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ログファイルを開けませんでした。: %w", err)
+	}
+	return slog.New(slog.NewJSONHandler(file, &slog.HandlerOptions{Level: level})), file, nil
+}
+
+// 標準出力にログを出すLoggerを返す。devcontainerやdocker logsで見るときに使う。
+// format "json"ならjsonで1行ずつ、それ以外はkey=valueの形式。
+func NewStdoutLogger(format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, opts))
+}
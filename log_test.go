@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+// 1ステップごとに処理の情報がログに出るか確認。
+func TestStepAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    error
+		attempt int
+		want    map[string]interface{}
+	}{
+		{
+			name:    "success",
+			attempt: 1,
+			want: map[string]interface{}{
+				"level":    "INFO",
+				"msg":      "処理が終わりました。",
+				"task":     "Click",
+				"selector": "#button",
+				"attempt":  float64(1),
+			},
+		},
+		{
+			name:    "failure",
+			args:    errors.New("失敗"),
+			attempt: 3,
+			want: map[string]interface{}{
+				"level":    "ERROR",
+				"msg":      "クリックできませんでした。",
+				"task":     "Click",
+				"selector": "#button",
+				"attempt":  float64(3),
+				"error":    "失敗",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			s := ScrapingTaskManager{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+			action := s.stepAction(step{Task: "Click", Sel: "#button", Failed: "クリックできませんでした。"}, func(ctx context.Context) error {
+				return tt.args
+			})
+			if err := action.Do(withAttempt(context.Background(), tt.attempt)); err != tt.args {
+				t.Errorf("stepAction() error = %v, want %v", err, tt.args)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := got["duration"]; !ok {
+				t.Errorf("stepAction() durationがありません。: %v", got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("stepAction() %s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ログインできなかった理由です。errors.Isで判定すること。
+var (
+	ErrAlreadyLoggedIn    = errors.New("すでにログインしています。")
+	ErrInvalidCredentials = errors.New("ユーザー名かパスワードが間違っています。")
+	ErrCaptchaRequired    = errors.New("画像認証が求められました。")
+	ErrTwoFactorRequired  = errors.New("2段階認証のコードが求められました。")
+	ErrLoginFailed        = errors.New("ログインできませんでした。")
+)
+
+// ログインボタンを押した後のページを調べて、ログインできたか確認する。
+// できていなければ、ページに表示されているものから理由を判断してエラーを返す。
+func (s ScrapingTaskManager) VerifyLoginTasks() chromedp.Tasks {
+	return chromedp.Tasks{
+		s.stepAction(step{Task: "VerifyLogin", Failed: "ログインできませんでした。"}, func(ctx context.Context) error {
+			var valid bool
+			if err := s.IsSessionVerificationTasks(&valid).Do(ctx); err != nil {
+				return err
+			}
+			if valid && s.LoginSuccessSel != "" {
+				var count int
+				if err := s.CountTasks(s.LoginSuccessSel, &count).Do(ctx); err != nil {
+					return err
+				}
+				valid = count > 0
+			}
+			if valid {
+				s.logger().Info("ログインできました。")
+				return nil
+			}
+
+			err := s.loginFailure(ctx)
+			s.TakeScreenShotLogTasks("html", "login_failed", "png").Do(ctx)
+			return err
+		}),
+	}
+}
+
+// ログインできなかったページに表示されているものから理由を判断する。
+func (s ScrapingTaskManager) loginFailure(ctx context.Context) error {
+	reasons := []struct {
+		sel Selector
+		err error
+	}{
+		// 画像認証や2段階認証はエラーメッセージと一緒に表示されることがあるので先に調べる。
+		{s.LoginCaptchaSel, ErrCaptchaRequired},
+		{s.LoginTwoFactorSel, ErrTwoFactorRequired},
+		{s.LoginErrorSel, ErrInvalidCredentials},
+	}
+	for _, r := range reasons {
+		if r.sel == "" {
+			continue
+		}
+		var count int
+		if err := s.CountTasks(r.sel, &count).Do(ctx); err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		if r.err != ErrInvalidCredentials {
+			return r.err
+		}
+		// エラーメッセージはそのまま含める。
+		var message string
+		if err := chromedp.Evaluate(withSelectorScript(fmt.Sprintf("find(%q).textContent.trim()", searchSelector(r.sel))), &message).Do(ctx); err != nil {
+			return r.err
+		}
+		return fmt.Errorf("%w: %s", r.err, message)
+	}
+
+	var href string
+	if err := s.LocationHrefTasks(&href).Do(ctx); err != nil {
+		return ErrLoginFailed
+	}
+	return fmt.Errorf("%w: %s", ErrLoginFailed, href)
+}
+
+// ログインしていなければログインする。何度呼んでも良い。
+// 1. トップページでセッションが有効か確認する。
+// 2. CookieFilePathに保存したcookieがあれば読み込んで、もう一度確認する。
+// 3. それでも無効ならLoginSiteTasksでログインし、CookieFilePathがあればcookieを保存する。
+// ログインはLoginRetries回までやり直す。ユーザー名やパスワードの間違いなど、やり直しても変わらない場合はやり直さない。
+func (s ScrapingTaskManager) EnsureLoggedInTasks() chromedp.Tasks {
+	return chromedp.Tasks{
+		s.stepAction(step{Task: "EnsureLoggedIn", Failed: "ログインできませんでした。", NoTimeout: true}, func(ctx context.Context) error {
+			valid, err := s.sessionValid(ctx)
+			if err != nil {
+				return err
+			}
+			if valid {
+				s.logger().Info("ログイン済みです。")
+				return nil
+			}
+
+			if s.CookieFilePath != "" {
+				err := s.LoadCookiesTasks(s.CookieFilePath).Do(ctx)
+				switch {
+				case err == nil:
+					valid, err := s.sessionValid(ctx)
+					if err != nil {
+						return err
+					}
+					if valid {
+						s.logger().Info("保存したcookieでログインできました。")
+						return nil
+					}
+				case errors.Is(err, os.ErrNotExist), errors.Is(err, ErrSessionExpired):
+					s.logger().Warn("保存したcookieは使えません。", "error", err)
+				default:
+					return err
+				}
+			}
+
+			for attempt := 0; ; attempt++ {
+				err = s.LoginSiteTasks().Do(withAttempt(ctx, attempt+1))
+				if err == nil || errors.Is(err, ErrAlreadyLoggedIn) {
+					break
+				}
+				if attempt >= s.LoginRetries || !retryableLoginError(err) {
+					return err
+				}
+				s.logger().Warn("ログインをやり直します。", "attempt", attempt+1, "retries", s.LoginRetries, "error", err)
+			}
+
+			if s.CookieFilePath != "" {
+				return s.SaveCookiesTasks(s.CookieFilePath).Do(ctx)
+			}
+			return nil
+		}),
+	}
+}
+
+// トップページに移動して、セッションが有効か確認する。
+// cookieはページのurlで絞られるので、サイトのページにいる必要がある。
+func (s ScrapingTaskManager) sessionValid(ctx context.Context) (bool, error) {
+	var valid bool
+	err := chromedp.Tasks{
+		s.MoveTopPageTasks(),
+		s.IsSessionVerificationTasks(&valid),
+	}.Do(ctx)
+	if err != nil {
+		s.logger().Error("ログイン済みかどうか確認できませんでした。", "error", err)
+		return false, err
+	}
+	return valid, nil
+}
+
+// やり直せばログインできるかもしれないエラーか。
+func retryableLoginError(err error) bool {
+	for _, e := range []error{ErrInvalidCredentials, ErrCaptchaRequired, ErrTwoFactorRequired} {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// ログインをやり直すべきエラーか判定できるか確認。
+func TestRetryableLoginError(t *testing.T) {
+	tests := []struct {
+		name string
+		args error
+		want bool
+	}{
+		{name: "invalid", args: fmt.Errorf("%w: パスワードが違います", ErrInvalidCredentials), want: false},
+		{name: "captcha", args: ErrCaptchaRequired, want: false},
+		{name: "two factor", args: ErrTwoFactorRequired, want: false},
+		{name: "unknown", args: fmt.Errorf("%w: https://login.dlsite.com/login", ErrLoginFailed), want: true},
+		{name: "timeout", args: context.DeadlineExceeded, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableLoginError(tt.args); got != tt.want {
+				t.Errorf("retryableLoginError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
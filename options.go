@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// *Tasksメソッドの呼び出しごとの設定です。Withに渡す。
+// example: s.With(WithTimeout(10*time.Second), WithRetry(&RetryPolicy{MaxAttempts: 3})).ClickTasks(sel)
+// 中で呼ぶ処理にも同じ設定が使われる。
+type TaskOption func(s *ScrapingTaskManager)
+
+// 処理の後に待つ条件。WaitLogicと同じ。
+func WithWait(w WaitLogic) TaskOption {
+	return func(s *ScrapingTaskManager) {
+		s.WaitLogic = w
+	}
+}
+
+// 処理1つごとのタイムアウト。TaskTimeoutと同じ。
+func WithTimeout(d time.Duration) TaskOption {
+	return func(s *ScrapingTaskManager) {
+		s.TaskTimeout = d
+	}
+}
+
+// 失敗した処理をやり直す設定。RetryPolicyと同じ。
+func WithRetry(policy *RetryPolicy) TaskOption {
+	return func(s *ScrapingTaskManager) {
+		s.RetryPolicy = policy
+	}
+}
+
+// 失敗したときに、fの設定でページの状態を保存する。FailureCaptureと同じ。nilなら保存しない。
+// コンソールのログはfに集めるので、呼び出しごとに作らずに同じものを使い回すこと。
+func WithScreenshotOnError(f *FailureCapture) TaskOption {
+	return func(s *ScrapingTaskManager) {
+		s.FailureCapture = f
+	}
+}
+
+// Selectorの解釈の仕方。chromedp.ByQuery, chromedp.ByID, chromedp.BySearch, chromedp.ByJSPathなど。
+// 指定しない場合はchromedpのデフォルトのBySearch。
+func WithQueryType(by chromedp.QueryOption) TaskOption {
+	return func(s *ScrapingTaskManager) {
+		s.queryOptions = append(append([]chromedp.QueryOption(nil), s.queryOptions...), by)
+	}
+}
+
+// iframeの中で要素を探す。frameはchromedp.Nodesで取得したiframeの要素。
+// BySearchとByJSPathでは使えないので、WithQueryTypeを指定しない場合はByQueryになる。
+func WithFrame(frame *cdp.Node) TaskOption {
+	return func(s *ScrapingTaskManager) {
+		opts := []chromedp.QueryOption{chromedp.ByQuery}
+		opts = append(opts, s.queryOptions...)
+		s.queryOptions = append(opts, chromedp.FromNode(frame))
+	}
+}
+
+// TaskOptionで設定を変えたコピーを返す。その呼び出しだけ設定を変えるのに使う。
+// example: s.With(WithTimeout(10*time.Second)).ClickTasks(sel, 2*time.Second)
+func (s ScrapingTaskManager) With(opts ...TaskOption) ScrapingTaskManager {
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package tasks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// TaskOptionから、呼び出しごとの設定になるか確認。
+func TestWith(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+	tests := []struct {
+		name  string
+		args  []TaskOption
+		check func(s ScrapingTaskManager) bool
+	}{
+		{name: "none", check: func(s ScrapingTaskManager) bool { return s.TaskTimeout == 0 }},
+		{
+			name:  "timeout",
+			args:  []TaskOption{WithTimeout(10 * time.Second)},
+			check: func(s ScrapingTaskManager) bool { return s.TaskTimeout == 10*time.Second },
+		},
+		{
+			name:  "retry",
+			args:  []TaskOption{WithRetry(policy), nil},
+			check: func(s ScrapingTaskManager) bool { return s.RetryPolicy == policy },
+		},
+		{
+			name:  "wait",
+			args:  []TaskOption{WithWait(WaitLoad(time.Second))},
+			check: func(s ScrapingTaskManager) bool { return s.WaitLogic != nil },
+		},
+		{
+			name:  "screenshot on error",
+			args:  []TaskOption{WithScreenshotOnError(&FailureCapture{})},
+			check: func(s ScrapingTaskManager) bool { return s.FailureCapture != nil },
+		},
+		{
+			name:  "query type and frame",
+			args:  []TaskOption{WithQueryType(chromedp.ByID), WithFrame(&cdp.Node{})},
+			check: func(s ScrapingTaskManager) bool { return len(s.queryOptions) == 3 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ScrapingTaskManager{DefaultTimeSpan: 2 * time.Second}
+			got := s.With(tt.args...)
+			if !tt.check(got) {
+				t.Errorf("With() = %v", got)
+			}
+			if got.DefaultTimeSpan != 2*time.Second {
+				t.Errorf("With() DefaultTimeSpan = %v, want %v", got.DefaultTimeSpan, 2*time.Second)
+			}
+			// 元の設定は変わらない。
+			if s.TaskTimeout != 0 || s.RetryPolicy != nil || s.FailureCapture != nil || len(s.queryOptions) != 0 {
+				t.Errorf("With() changed the original %v", s)
+			}
+		})
+	}
+}
+
+// 待つ時間を渡せる*Tasksメソッドは、全て最後の引数が t ...time.Duration であることの確認。
+// 設定はWithで渡すので、型の無い引数で受け取らない。
+func TestTasksWaitArgument(t *testing.T) {
+	st := reflect.TypeOf(ScrapingTaskManager{})
+	durations := reflect.TypeOf([]time.Duration(nil))
+	for i := 0; i < st.NumMethod(); i++ {
+		m := st.Method(i)
+		if !strings.HasSuffix(m.Name, "Tasks") || !m.Type.IsVariadic() {
+			continue
+		}
+		if last := m.Type.In(m.Type.NumIn() - 1); last != durations {
+			t.Errorf("%s() の最後の引数 = %v, want %v", m.Name, last, durations)
+		}
+	}
+}
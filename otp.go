@@ -0,0 +1,157 @@
+package tasks
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 2段階認証のコードを返します。
+// ScrapingTaskManagerのOTPProviderに設定すると、LoginSiteTasksでコードが求められたときに使われる。
+type OTPProvider interface {
+	OTP(ctx context.Context) (string, error)
+}
+
+// 決まったコードを返すOTPProviderです。
+type StaticOTP string
+
+func (o StaticOTP) OTP(ctx context.Context) (string, error) {
+	return string(o), nil
+}
+
+// 関数でコードを返すOTPProviderです。
+type OTPFunc func(ctx context.Context) (string, error)
+
+func (f OTPFunc) OTP(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// 認証アプリと同じコード(RFC 6238)を計算するOTPProviderです。
+type TOTP struct {
+	Secret string           // base32の秘密鍵。認証アプリに登録するときに表示されるもの。
+	Digits int              // 桁数。0なら6。1から9まで。
+	Period time.Duration    // コードが変わる間隔。0なら30秒。1秒より短くはできない。
+	Now    func() time.Time // 現在時刻。nilならtime.Now。
+}
+
+func (o TOTP) OTP(ctx context.Context) (string, error) {
+	secret := strings.ToUpper(strings.ReplaceAll(o.Secret, " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("TOTPの秘密鍵を解釈できませんでした。: %w", err)
+	}
+
+	digits, period, now := o.Digits, o.Period, time.Now
+	if digits == 0 {
+		digits = 6
+	}
+	if period == 0 {
+		period = 30 * time.Second
+	}
+	// 秒で数えるので、1秒より短いと0で割ることになる。
+	if period < time.Second {
+		return "", fmt.Errorf("TOTPのPeriodは1秒以上にしてください。: %v", period)
+	}
+	// 10桁以上はコードの31bitに収まらない。
+	if digits < 1 || digits > 9 {
+		return "", fmt.Errorf("TOTPのDigitsは1から9にしてください。: %d", digits)
+	}
+	if o.Now != nil {
+		now = o.Now
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(now().Unix()/int64(period/time.Second)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod), nil
+}
+
+// 入力されたコードを返すOTPProviderです。
+// wに入力を促すメッセージを書いて、rから1行読む。標準入力から読むならPromptOTP(os.Stdin, os.Stderr)。
+func PromptOTP(r io.Reader, w io.Writer) OTPFunc {
+	type result struct {
+		line string
+		err  error
+	}
+	reader := bufio.NewReader(r)
+	// ctxが終わって読むのをやめた行。次の呼び出しで受け取る。
+	var pending chan result
+	return func(ctx context.Context) (string, error) {
+		fmt.Fprint(w, "2段階認証のコードを入力してください: ")
+		if pending == nil {
+			pending = make(chan result, 1)
+			go func(ch chan<- result) {
+				line, err := reader.ReadString('\n')
+				ch <- result{line, err}
+			}(pending)
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("2段階認証のコードを読み込めませんでした。: %w", ctx.Err())
+		case res := <-pending:
+			pending = nil
+			if res.err != nil && res.line == "" {
+				return "", fmt.Errorf("2段階認証のコードを読み込めませんでした。: %w", res.err)
+			}
+			return strings.TrimSpace(res.line), nil
+		}
+	}
+}
+
+// 2段階認証のコードが求められていたら、OTPProviderのコードを入力して送信する。
+// 求められていないか、OTPProviderが無ければ何もしない。その場合はVerifyLoginTasksがErrTwoFactorRequiredを返す。
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) TwoFactorTasks(t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+	return chromedp.Tasks{
+		s.stepAction(step{Task: "TwoFactor", Sel: s.LoginTwoFactorSel, Failed: "2段階認証のコードを送信できませんでした。", NoTimeout: true}, func(ctx context.Context) error {
+			if s.LoginTwoFactorSel == "" || s.OTPProvider == nil {
+				return nil
+			}
+			var count int
+			if err := s.CountTasks(s.LoginTwoFactorSel, &count).Do(ctx); err != nil {
+				return err
+			}
+			if count == 0 {
+				return nil
+			}
+
+			s.logger().Info("2段階認証のコードが求められました。")
+			code, err := s.OTPProvider.OTP(ctx)
+			if err != nil {
+				return fmt.Errorf("2段階認証のコードを取得できませんでした。: %w", err)
+			}
+			if err := s.SendKeysTasks(s.LoginTwoFactorSel, code, waitTime).Do(ctx); err != nil {
+				return err
+			}
+			// 送信ボタンが無ければEnterで送信する。
+			if s.LoginOTPButtonSel == "" {
+				return s.SendKeysTasks(s.LoginTwoFactorSel, "\r", waitTime).Do(ctx)
+			}
+			return s.ClickTasks(s.LoginOTPButtonSel, waitTime).Do(ctx)
+		}),
+	}
+}
@@ -0,0 +1,122 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238のテストベクターと同じコードになるか確認。
+func TestTOTP(t *testing.T) {
+	// "12345678901234567890"をbase32にしたもの。
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		name string
+		args int64
+		want string
+	}{
+		{name: "59", args: 59, want: "94287082"},
+		{name: "1111111109", args: 1111111109, want: "07081804"},
+		{name: "1111111111", args: 1111111111, want: "14050471"},
+		{name: "1234567890", args: 1234567890, want: "89005924"},
+		{name: "2000000000", args: 2000000000, want: "69279037"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := TOTP{Secret: secret, Digits: 8, Now: func() time.Time { return time.Unix(tt.args, 0) }}
+			got, err := o.OTP(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("TOTP.OTP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 1秒より短いPeriodをエラーにすることの確認。
+func TestTOTPPeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    time.Duration
+		wantErr bool
+	}{
+		{name: "default", args: 0},
+		{name: "1s", args: time.Second},
+		{name: "500ms", args: 500 * time.Millisecond, wantErr: true},
+		{name: "negative", args: -time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := TOTP{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Period: tt.args}
+			if _, err := o.OTP(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("TOTP.OTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 1から9以外のDigitsをエラーにすることの確認。
+func TestTOTPDigits(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    int
+		wantLen int
+		wantErr bool
+	}{
+		{name: "default", args: 0, wantLen: 6},
+		{name: "1", args: 1, wantLen: 1},
+		{name: "9", args: 9, wantLen: 9},
+		{name: "10", args: 10, wantErr: true},
+		{name: "negative", args: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := TOTP{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Digits: tt.args}
+			got, err := o.OTP(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TOTP.OTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("TOTP.OTP() = %v, want %d桁", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+// 入力された1行がコードになるか確認。
+func TestPromptOTP(t *testing.T) {
+	var w strings.Builder
+	got, err := PromptOTP(strings.NewReader(" 123456 \n"), &w).OTP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "123456" {
+		t.Errorf("PromptOTP() = %v, want %v", got, "123456")
+	}
+}
+
+// 入力を待っている間にctxが終わったらエラーになり、後から入力された行は次の呼び出しで読めることの確認。
+func TestPromptOTPCancel(t *testing.T) {
+	r, w := io.Pipe()
+	prompt := PromptOTP(r, io.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := prompt.OTP(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("PromptOTP() error = %v, want %v", err, context.Canceled)
+	}
+
+	go w.Write([]byte("123456\n"))
+	got, err := prompt.OTP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "123456" {
+		t.Errorf("PromptOTP() = %v, want %v", got, "123456")
+	}
+}
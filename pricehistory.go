@@ -0,0 +1,179 @@
+package tasks
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 価格の履歴を入れるbucketの名前。中に作品IDごとのbucketがある。
+var priceBucket = []byte("prices")
+
+// ある時点の作品の価格です。
+type PricePoint struct {
+	ProductID string
+	Time      time.Time
+	ListPrice int // 定価(円)
+	SalePrice int // セール価格(円)。セール中でなければ0。
+	Discount  int // 割引率(%)
+	Points    int // 付与されるポイント
+}
+
+// 実際に払う価格。
+func (p PricePoint) Price() int {
+	if p.SalePrice > 0 {
+		return p.SalePrice
+	}
+	return p.ListPrice
+}
+
+// セールが始まったか、セール価格が下がったことを表します。
+type SaleEvent struct {
+	PricePoint
+	PrevPrice int // 直前に記録した価格。初めて記録した作品なら0。
+}
+
+// 作品の価格の履歴をローカルのファイルに保存するストアです。
+// ScrapingTaskManagerのPriceStoreに設定すると、ScrapeWorkTasksで取得するたびに記録される。
+type PriceStore struct {
+	db *bolt.DB
+}
+
+// 価格の履歴のファイルを開く。無ければ作る。
+// 使い終わったらCloseすること。
+func OpenPriceStore(path string) (*PriceStore, error) {
+	db, err := bolt.Open(path, 0640, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("価格の履歴を開けませんでした。: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(priceBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("価格の履歴を初期化できませんでした。: %w", err)
+	}
+	return &PriceStore{db: db}, nil
+}
+
+func (p *PriceStore) Close() error {
+	return p.db.Close()
+}
+
+// 価格を記録する。
+func (p *PriceStore) Record(point PricePoint) error {
+	if point.ProductID == "" {
+		return fmt.Errorf("作品IDが無い価格は記録できません。")
+	}
+	v, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(priceBucket).CreateBucketIfNotExists([]byte(point.ProductID))
+		if err != nil {
+			return err
+		}
+		// 時刻の順に並ぶようにunix時間をキーにする。
+		// 同じ時刻でも上書きしないように、後ろに作品ごとの連番をつける。
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 16)
+		binary.BigEndian.PutUint64(key, uint64(point.Time.UnixNano()))
+		binary.BigEndian.PutUint64(key[8:], seq)
+		return b.Put(key, v)
+	})
+}
+
+// 取得した作品の価格を記録する。
+func (p *PriceStore) RecordWork(w Work, at time.Time) error {
+	return p.Record(PricePoint{
+		ProductID: w.ProductID,
+		Time:      at,
+		ListPrice: w.Price,
+		SalePrice: w.SalePrice,
+		Discount:  discountRate(w.Price, w.SalePrice),
+		Points:    w.Points,
+	})
+}
+
+// 作品の価格の履歴を古い順に返す。
+func (p *PriceStore) PriceHistory(productID string) ([]PricePoint, error) {
+	var points []PricePoint
+	err := p.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(priceBucket).Bucket([]byte(productID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var point PricePoint
+			if err := json.Unmarshal(v, &point); err != nil {
+				return err
+			}
+			points = append(points, point)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("価格の履歴を読み込めませんでした。: %w", err)
+	}
+	return points, nil
+}
+
+// since以降に記録した価格のうち、セールが始まったかセール価格が下がったものを返す。
+// 作品IDの順、同じ作品なら古い順に並ぶ。
+func (p *PriceStore) DetectSales(since time.Time) ([]SaleEvent, error) {
+	var events []SaleEvent
+	err := p.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(priceBucket).ForEachBucket(func(id []byte) error {
+			var prev *PricePoint
+			return tx.Bucket(priceBucket).Bucket(id).ForEach(func(k, v []byte) error {
+				var point PricePoint
+				if err := json.Unmarshal(v, &point); err != nil {
+					return err
+				}
+				if !point.Time.Before(since) && isSaleStart(prev, point) {
+					event := SaleEvent{PricePoint: point}
+					if prev != nil {
+						event.PrevPrice = prev.Price()
+					}
+					events = append(events, event)
+				}
+				prev = &point
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("価格の履歴を読み込めませんでした。: %w", err)
+	}
+	return events, nil
+}
+
+// PriceStoreに記録した価格から、since以降に始まったセールを返す。
+// PriceStoreが無ければエラー。
+func (s ScrapingTaskManager) DetectSales(since time.Time) ([]SaleEvent, error) {
+	if s.PriceStore == nil {
+		return nil, fmt.Errorf("PriceStoreが設定されていません。")
+	}
+	events, err := s.PriceStore.DetectSales(since)
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info("セールを探しました。", "since", since.Format(time.RFC3339), "count", len(events))
+	return events, nil
+}
+
+// 直前の価格からセールが始まったか、セール価格が下がったか。
+func isSaleStart(prev *PricePoint, cur PricePoint) bool {
+	if cur.SalePrice == 0 {
+		return false
+	}
+	return prev == nil || cur.Price() < prev.Price()
+}
@@ -0,0 +1,91 @@
+package tasks
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 価格の履歴が記録され、セールが検出されるか確認。
+func TestPriceStore(t *testing.T) {
+	store, err := OpenPriceStore(filepath.Join(t.TempDir(), "prices.db"))
+	if err != nil {
+		t.Fatalf("OpenPriceStore() = %v", err)
+	}
+	defer store.Close()
+
+	day := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	works := []struct {
+		work Work
+		at   time.Time
+	}{
+		{Work{ProductID: "RJ01000000", Price: 1320, Points: 120}, day},
+		{Work{ProductID: "RJ01000000", Price: 1320, SalePrice: 990, Points: 90}, day.Add(24 * time.Hour)},
+		{Work{ProductID: "RJ01000000", Price: 1320, SalePrice: 660, Points: 60}, day.Add(48 * time.Hour)},
+		{Work{ProductID: "RJ01000001", Price: 880}, day},
+		{Work{ProductID: "RJ01000001", Price: 880}, day.Add(48 * time.Hour)},
+		{Work{ProductID: "RJ01000002", Price: 2200, SalePrice: 1100}, day.Add(48 * time.Hour)},
+	}
+	for _, w := range works {
+		if err := store.RecordWork(w.work, w.at); err != nil {
+			t.Fatalf("RecordWork() = %v", err)
+		}
+	}
+
+	history, err := store.PriceHistory("RJ01000000")
+	if err != nil {
+		t.Fatalf("PriceHistory() = %v", err)
+	}
+	if len(history) != 3 || history[1].Discount != 25 || history[2].Price() != 660 {
+		t.Errorf("PriceHistory() = %+v", history)
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{name: "all", since: day, want: []string{"RJ01000000", "RJ01000000", "RJ01000002"}},
+		{name: "last", since: day.Add(48 * time.Hour), want: []string{"RJ01000000", "RJ01000002"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := store.DetectSales(tt.since)
+			if err != nil {
+				t.Fatalf("DetectSales() = %v", err)
+			}
+			var got []string
+			for _, e := range events {
+				got = append(got, e.ProductID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectSales() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 同じ時刻に記録しても上書きされないことの確認。
+func TestPriceStoreSameTime(t *testing.T) {
+	store, err := OpenPriceStore(filepath.Join(t.TempDir(), "prices.db"))
+	if err != nil {
+		t.Fatalf("OpenPriceStore() = %v", err)
+	}
+	defer store.Close()
+
+	at := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, price := range []int{1320, 990} {
+		if err := store.Record(PricePoint{ProductID: "RJ01000000", Time: at, ListPrice: price}); err != nil {
+			t.Fatalf("Record() = %v", err)
+		}
+	}
+
+	history, err := store.PriceHistory("RJ01000000")
+	if err != nil {
+		t.Fatalf("PriceHistory() = %v", err)
+	}
+	if len(history) != 2 || history[0].ListPrice != 1320 || history[1].ListPrice != 990 {
+		t.Errorf("PriceHistory() = %+v, want 2件", history)
+	}
+}
@@ -0,0 +1,251 @@
+package tasks
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// サイトごとに異なるurlとセレクタの組です。
+// フィールド名はScrapingTaskManagerと同じ。
+// 空文字のフィールドはBaseのプロファイルの値を引き継ぐので、違うところだけ書けば良い。
+type SiteProfile struct {
+	Base                  string // 引き継ぐプロファイルの名前。空文字なら引き継がない。
+	SiteSessionCookieName string
+	SiteTopUrl            string
+	LogInUrl              string
+	LogOutUrl             string
+	LoginUsernameSel      Selector
+	LoginPasswordSel      Selector
+	LoginButtonSel        Selector
+	LoginSuccessSel       Selector
+	LoginErrorSel         Selector
+	LoginCaptchaSel       Selector
+	LoginTwoFactorSel     Selector
+	LoginOTPButtonSel     Selector
+	AgePermissionUrl      string
+	AgePermissionSel      Selector
+	AgePermissionNextSel  Selector
+	WorkUrl               string
+	WorkTitleSel          Selector
+	WorkMakerSel          Selector
+	WorkPriceSel          Selector
+	WorkRegularPriceSel   Selector
+	WorkPointSel          Selector
+	WorkOutlineSel        Selector
+	WorkGenreSel          Selector
+	WorkSampleImageSel    Selector
+	WorkDescriptionSel    Selector
+	SearchUrl             string
+	SearchItemSel         Selector
+	SearchItemTitleSel    Selector
+	SearchItemPriceSel    Selector
+	SearchItemRatingSel   Selector
+	SearchNextSel         Selector
+	RankingUrl            string
+	RankingItemSel        Selector
+	RankingItemRankSel    Selector
+	RankingItemTitleSel   Selector
+	RankingItemMakerSel   Selector
+	RankingItemPriceSel   Selector
+	RankingItemSalesSel   Selector
+	PurchaseHistoryUrl    string
+	PurchaseItemSel       Selector
+	PurchaseItemTitleSel  Selector
+	PurchaseItemMakerSel  Selector
+	PurchaseItemDateSel   Selector
+	PurchaseItemPriceSel  Selector
+	PurchaseDownloadSel   Selector
+	PurchaseNextSel       Selector
+	WishlistUrl           string
+	WishlistItemSel       Selector
+	WishlistItemTitleSel  Selector
+	WishlistItemMakerSel  Selector
+	WishlistItemPriceSel  Selector
+	WishlistRegularSel    Selector
+	WishlistNextSel       Selector
+}
+
+// 組み込みのプロファイルです。
+// dlsiteが共通の設定で、各フロアはdlsiteを引き継ぐ。
+var BuiltinProfiles = map[string]SiteProfile{
+	"dlsite": {
+		SiteSessionCookieName: "session_state",
+		SiteTopUrl:            "https://www.dlsite.com/",
+		LogInUrl:              "https://login.dlsite.com/login?user=self",
+		LogOutUrl:             "https://www.dlsite.com/home/logout",
+		LoginUsernameSel:      "#form_id",
+		LoginPasswordSel:      "#form_password",
+		LoginButtonSel:        "body > div.l-container > div > div > section > div.mainBox-body > div.contentBox > div:nth-child(1) > div > form > div.loginBtn > button",
+		LoginErrorSel:         ".loginBox .error_message, .mainBox-body .error",
+		LoginCaptchaSel:       "iframe[src*=\"recaptcha\"]",
+		LoginTwoFactorSel:     "input[autocomplete=\"one-time-code\"]",
+		AgePermissionUrl:      "https://www.dlsite.com/maniax/",
+		AgePermissionSel:      "body > div.adult_check_box > div > ul > li.btn_yes > a",
+		AgePermissionNextSel:  "#top_header",
+		WorkUrl:               "https://www.dlsite.com/maniax/work/=/product_id/%s.html",
+		WorkTitleSel:          "#work_name",
+		WorkMakerSel:          "#work_maker .maker_name a",
+		WorkPriceSel:          "#work_buy_box_wrapper .work_buy_content .price",
+		WorkRegularPriceSel:   "#work_buy_box_wrapper .work_buy_content .strike",
+		WorkPointSel:          "#work_buy_box_wrapper .work_buy_content .work_point",
+		WorkOutlineSel:        "#work_outline tr",
+		WorkGenreSel:          "#work_outline .main_genre a",
+		WorkSampleImageSel:    ".product-slider-data > div",
+		WorkDescriptionSel:    ".work_parts_container",
+		SearchUrl:             "https://www.dlsite.com/maniax/fsr/=",
+		SearchItemSel:         "#search_result_list > ul > li",
+		SearchItemTitleSel:    ".work_name a",
+		SearchItemPriceSel:    ".work_price",
+		SearchItemRatingSel:   ".star_rating",
+		SearchNextSel:         ".page_no a[rel=\"next\"]",
+		RankingUrl:            "https://www.dlsite.com/maniax/ranking",
+		RankingItemSel:        "#ranking_table > tbody > tr",
+		RankingItemRankSel:    ".rank_no",
+		RankingItemTitleSel:   ".work_name a",
+		RankingItemMakerSel:   ".maker_name a",
+		RankingItemPriceSel:   ".work_price",
+		RankingItemSalesSel:   ".work_dl",
+		PurchaseHistoryUrl:    "https://www.dlsite.com/maniax/mypage/userbuy",
+		PurchaseItemSel:       ".work_list_main > tbody > tr",
+		PurchaseItemTitleSel:  ".work_name a",
+		PurchaseItemMakerSel:  ".maker_name a",
+		PurchaseItemDateSel:   ".buy_date",
+		PurchaseItemPriceSel:  ".work_price",
+		PurchaseDownloadSel:   ".work_dl_btn a",
+		PurchaseNextSel:       ".page_no a[rel=\"next\"]",
+		WishlistUrl:           "https://www.dlsite.com/maniax/mypage/wishlist",
+		WishlistItemSel:       "#wishlist_work > tbody > tr",
+		WishlistItemTitleSel:  ".work_name a",
+		WishlistItemMakerSel:  ".maker_name a",
+		WishlistItemPriceSel:  ".work_price",
+		WishlistRegularSel:    ".strike",
+		WishlistNextSel:       ".page_no a[rel=\"next\"]",
+	},
+	// 同人(全年齢)
+	"home": {
+		Base:               "dlsite",
+		SiteTopUrl:         "https://www.dlsite.com/home/",
+		LogOutUrl:          "https://www.dlsite.com/home/logout",
+		AgePermissionUrl:   "https://www.dlsite.com/home/",
+		WorkUrl:            "https://www.dlsite.com/home/work/=/product_id/%s.html",
+		SearchUrl:          "https://www.dlsite.com/home/fsr/=",
+		RankingUrl:         "https://www.dlsite.com/home/ranking",
+		PurchaseHistoryUrl: "https://www.dlsite.com/home/mypage/userbuy",
+		WishlistUrl:        "https://www.dlsite.com/home/mypage/wishlist",
+	},
+	// 同人(成人向け)
+	"maniax": {
+		Base:               "dlsite",
+		SiteTopUrl:         "https://www.dlsite.com/maniax/",
+		LogOutUrl:          "https://www.dlsite.com/maniax/logout",
+		AgePermissionUrl:   "https://www.dlsite.com/maniax/",
+		WorkUrl:            "https://www.dlsite.com/maniax/work/=/product_id/%s.html",
+		SearchUrl:          "https://www.dlsite.com/maniax/fsr/=",
+		RankingUrl:         "https://www.dlsite.com/maniax/ranking",
+		PurchaseHistoryUrl: "https://www.dlsite.com/maniax/mypage/userbuy",
+		WishlistUrl:        "https://www.dlsite.com/maniax/mypage/wishlist",
+	},
+	// 成年コミック
+	"books": {
+		Base:               "dlsite",
+		SiteTopUrl:         "https://www.dlsite.com/books/",
+		LogOutUrl:          "https://www.dlsite.com/books/logout",
+		AgePermissionUrl:   "https://www.dlsite.com/books/",
+		WorkUrl:            "https://www.dlsite.com/books/work/=/product_id/%s.html",
+		SearchUrl:          "https://www.dlsite.com/books/fsr/=",
+		RankingUrl:         "https://www.dlsite.com/books/ranking",
+		PurchaseHistoryUrl: "https://www.dlsite.com/books/mypage/userbuy",
+		WishlistUrl:        "https://www.dlsite.com/books/mypage/wishlist",
+	},
+	// 美少女ゲーム
+	"pro": {
+		Base:               "dlsite",
+		SiteTopUrl:         "https://www.dlsite.com/pro/",
+		LogOutUrl:          "https://www.dlsite.com/pro/logout",
+		AgePermissionUrl:   "https://www.dlsite.com/pro/",
+		WorkUrl:            "https://www.dlsite.com/pro/work/=/product_id/%s.html",
+		SearchUrl:          "https://www.dlsite.com/pro/fsr/=",
+		RankingUrl:         "https://www.dlsite.com/pro/ranking",
+		PurchaseHistoryUrl: "https://www.dlsite.com/pro/mypage/userbuy",
+		WishlistUrl:        "https://www.dlsite.com/pro/mypage/wishlist",
+	},
+}
+
+// 名前からプロファイルを探して、Baseを辿って値を埋めたものを返す。
+// profilesにあるものはBuiltinProfilesより優先される。
+func ResolveProfile(name string, profiles map[string]SiteProfile) (SiteProfile, error) {
+	visited := map[string]bool{}
+	var chain []SiteProfile
+	for name != "" {
+		if visited[name] {
+			return SiteProfile{}, fmt.Errorf("プロファイルのBaseが循環しています。: %s", name)
+		}
+		visited[name] = true
+
+		p, ok := profiles[name]
+		if !ok {
+			p, ok = BuiltinProfiles[name]
+		}
+		if !ok {
+			return SiteProfile{}, fmt.Errorf("プロファイルが見つかりませんでした。: %s", name)
+		}
+		chain = append(chain, p)
+		name = p.Base
+	}
+
+	// 根元から順に上書きする。
+	var resolved SiteProfile
+	for i := len(chain) - 1; i >= 0; i-- {
+		resolved = resolved.merge(chain[i])
+	}
+	resolved.Base = ""
+	return resolved, nil
+}
+
+// 組み込みとユーザー定義のプロファイルの名前を全て返す。
+func ProfileNames(profiles map[string]SiteProfile) []string {
+	var names []string
+	for name := range BuiltinProfiles {
+		names = append(names, name)
+	}
+	for name := range profiles {
+		if _, ok := BuiltinProfiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pの空文字でないフィールドでoverrideした値を返す。
+func (p SiteProfile) merge(override SiteProfile) SiteProfile {
+	dst := reflect.ValueOf(&p).Elem()
+	src := reflect.ValueOf(override)
+	for i := 0; i < src.NumField(); i++ {
+		if v := src.Field(i); !v.IsZero() {
+			dst.Field(i).Set(v)
+		}
+	}
+	return p
+}
+
+// プロファイルの空文字でないフィールドで上書きしたScrapingTaskManagerを返す。
+// 同じ設定のままフロアを切り替えるのに使う。
+//
+//	maniax, _ := ResolveProfile("maniax", nil)
+//	s = s.WithProfile(maniax)
+func (s ScrapingTaskManager) WithProfile(p SiteProfile) ScrapingTaskManager {
+	dst := reflect.ValueOf(&s).Elem()
+	src := reflect.ValueOf(p)
+	for i := 0; i < src.NumField(); i++ {
+		name := src.Type().Field(i).Name
+		if name == "Base" {
+			continue
+		}
+		if v := src.Field(i); !v.IsZero() {
+			dst.FieldByName(name).Set(v)
+		}
+	}
+	return s
+}
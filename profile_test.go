@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"reflect"
+	"testing"
+)
+
+// SiteProfileのフィールドが全てScrapingTaskManagerにあるか確認。
+// 無いとWithProfileがpanicする。
+func TestSiteProfileFields(t *testing.T) {
+	typ := reflect.TypeOf(SiteProfile{})
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Name == "Base" {
+			continue
+		}
+		mf, ok := reflect.TypeOf(ScrapingTaskManager{}).FieldByName(f.Name)
+		if !ok || mf.Type != f.Type {
+			t.Errorf("ScrapingTaskManagerに%sがありません。", f.Name)
+		}
+	}
+}
+
+// Baseを辿ってプロファイルが解決されるか確認。
+func TestResolveProfile(t *testing.T) {
+	profiles := map[string]SiteProfile{
+		"mybooks": {
+			Base:       "books",
+			SiteTopUrl: "https://www.dlsite.com/books/?locale=ja_JP",
+		},
+		"loop-a": {Base: "loop-b"},
+		"loop-b": {Base: "loop-a"},
+	}
+
+	tests := []struct {
+		name    string
+		args    string
+		want    SiteProfile
+		wantErr bool
+	}{
+		{
+			name: "builtin",
+			args: "maniax",
+			want: SiteProfile{
+				SiteTopUrl:       "https://www.dlsite.com/maniax/",
+				LogInUrl:         BuiltinProfiles["dlsite"].LogInUrl,
+				AgePermissionUrl: "https://www.dlsite.com/maniax/",
+			},
+		},
+		{
+			name: "user",
+			args: "mybooks",
+			want: SiteProfile{
+				SiteTopUrl:       "https://www.dlsite.com/books/?locale=ja_JP",
+				LogInUrl:         BuiltinProfiles["dlsite"].LogInUrl,
+				AgePermissionUrl: "https://www.dlsite.com/books/",
+			},
+		},
+		{name: "loop", args: "loop-a", wantErr: true},
+		{name: "missing", args: "nothing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveProfile(tt.args, profiles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.SiteTopUrl != tt.want.SiteTopUrl || got.LogInUrl != tt.want.LogInUrl || got.AgePermissionUrl != tt.want.AgePermissionUrl {
+				t.Errorf("ResolveProfile() = %+v, want %+v", got, tt.want)
+			}
+			if got.Base != "" {
+				t.Errorf("ResolveProfile() Base = %s, want empty", got.Base)
+			}
+		})
+	}
+}
+
+// 設定ファイルで選んだプロファイルに、ファイルの値が上書きされるか確認。
+func TestLoadConfigProfile(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := writeConfig(t, "dlsite.yaml", `
+Profile: mypro
+Profiles:
+  mypro:
+    Base: pro
+    AgePermissionNextSel: "#pro_header"
+LogOutUrl: https://www.dlsite.com/pro/logout?return=top
+`)
+
+	s, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	if s.SiteTopUrl != "https://www.dlsite.com/pro/" {
+		t.Errorf("LoadConfig() SiteTopUrl = %s, want %s", s.SiteTopUrl, "https://www.dlsite.com/pro/")
+	}
+	if s.AgePermissionNextSel != "#pro_header" {
+		t.Errorf("LoadConfig() AgePermissionNextSel = %s, want %s", s.AgePermissionNextSel, "#pro_header")
+	}
+	if s.LogOutUrl != "https://www.dlsite.com/pro/logout?return=top" {
+		t.Errorf("LoadConfig() LogOutUrl = %s, want %s", s.LogOutUrl, "https://www.dlsite.com/pro/logout?return=top")
+	}
+
+	// 引数のプロファイルが優先される。
+	s, err = LoadProfileConfig(path, "books")
+	if err != nil {
+		t.Fatalf("LoadProfileConfig() = %v", err)
+	}
+	if s.SiteTopUrl != "https://www.dlsite.com/books/" {
+		t.Errorf("LoadProfileConfig() SiteTopUrl = %s, want %s", s.SiteTopUrl, "https://www.dlsite.com/books/")
+	}
+}
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 購入済みの作品です。
+type Purchase struct {
+	ProductID    string
+	Title        string
+	Maker        string
+	PurchaseDate time.Time
+	PricePaid    int  // 支払った価格(円)
+	Downloadable bool // ダウンロードできるか
+}
+
+func (p Purchase) CSVHeader() []string {
+	return []string{"product_id", "title", "maker", "purchase_date", "price_paid", "downloadable"}
+}
+
+func (p Purchase) CSVRecord() []string {
+	var date string
+	if !p.PurchaseDate.IsZero() {
+		date = p.PurchaseDate.Format("2006-01-02")
+	}
+	return []string{p.ProductID, p.Title, p.Maker, date, strconv.Itoa(p.PricePaid), strconv.FormatBool(p.Downloadable)}
+}
+
+// 購入履歴のページを辿って、購入済みの作品の一覧を取得する。
+// ログインしてから呼ぶこと。最後のページまで辿る。
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) PurchaseHistoryTasks(out *[]Purchase, t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+	if err := requireUrl("PurchaseHistoryUrl", s.PurchaseHistoryUrl); err != nil {
+		return s.ErrorTask(err.Error())
+	}
+
+	fields := s.purchaseFields()
+	return chromedp.Tasks{
+		s.MovePageTasks(s.PurchaseHistoryUrl, waitTime),
+		s.paginateTasks(s.PurchaseNextSel, 0, waitTime, func(ctx context.Context) error {
+			var items []listItem
+			if err := s.listTasks(s.PurchaseItemSel, fields, &items).Do(ctx); err != nil {
+				return err
+			}
+			*out = append(*out, purchases(items)...)
+			return nil
+		}),
+	}
+}
+
+// 購入履歴の1件の中で取得するフィールド。
+// Selectorが空だと1件分の要素そのものを使うので、ダウンロードボタンは空なら取得しない。
+// 取得しなければ、Downloadableはfalseになる。
+func (s ScrapingTaskManager) purchaseFields() map[string]Selector {
+	fields := map[string]Selector{
+		"Title": s.PurchaseItemTitleSel,
+		"Maker": s.PurchaseItemMakerSel,
+		"Date":  s.PurchaseItemDateSel,
+		"Price": s.PurchaseItemPriceSel,
+	}
+	if s.PurchaseDownloadSel != "" {
+		fields["Download"] = s.PurchaseDownloadSel
+	}
+	return fields
+}
+
+// 一覧ページの各項目をPurchaseにする。作品IDが無いものは飛ばす。
+func purchases(items []listItem) []Purchase {
+	var ps []Purchase
+	for _, item := range items {
+		id := ProductIDFromURL(item["Title"].Href)
+		if id == "" {
+			continue
+		}
+		date, _ := parseDate(item["Date"].Text)
+		// ダウンロードボタンが見つかったときだけ、項目がある。
+		_, downloadable := item["Download"]
+		ps = append(ps, Purchase{
+			ProductID:    id,
+			Title:        item["Title"].Text,
+			Maker:        item["Maker"].Text,
+			PurchaseDate: date,
+			PricePaid:    parsePrice(item["Price"].Text),
+			Downloadable: downloadable,
+		})
+	}
+	return ps
+}
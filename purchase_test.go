@@ -0,0 +1,132 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 一覧ページの項目がPurchaseになるか確認。
+func TestPurchases(t *testing.T) {
+	items := []listItem{
+		{
+			"Title":    {Text: "作品名", Href: "https://www.dlsite.com/maniax/work/=/product_id/RJ01000000.html"},
+			"Maker":    {Text: "サークル名"},
+			"Date":     {Text: "2023/07/01 12:34"},
+			"Price":    {Text: "1,320円"},
+			"Download": {Text: "ダウンロード"},
+		},
+		{
+			"Title": {Text: "販売終了作品", Href: "https://www.dlsite.com/maniax/work/=/product_id/RJ01000001.html"},
+			"Date":  {Text: "2023/06/01 01:00"},
+			"Price": {Text: "880円"},
+		},
+	}
+	want := []Purchase{
+		{
+			ProductID:    "RJ01000000",
+			Title:        "作品名",
+			Maker:        "サークル名",
+			PurchaseDate: time.Date(2023, 7, 1, 0, 0, 0, 0, jst),
+			PricePaid:    1320,
+			Downloadable: true,
+		},
+		{
+			ProductID:    "RJ01000001",
+			Title:        "販売終了作品",
+			PurchaseDate: time.Date(2023, 6, 1, 0, 0, 0, 0, jst),
+			PricePaid:    880,
+		},
+	}
+	if got := purchases(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("purchases() = %+v, want %+v", got, want)
+	}
+}
+
+// ダウンロードボタンのSelectorが空なら、取得しないことの確認。
+func TestPurchaseFields(t *testing.T) {
+	tests := []struct {
+		name string
+		args Selector
+		want bool
+	}{
+		{name: "set", args: ".btn_dl", want: true},
+		// 空なら1件分の要素そのものに合致してしまうので取得しない。
+		{name: "empty", args: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := ScrapingTaskManager{PurchaseDownloadSel: tt.args}.purchaseFields()
+			if _, got := fields["Download"]; got != tt.want {
+				t.Errorf("purchaseFields() Download = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// csvとjsonで書き出せるか確認。
+func TestExport(t *testing.T) {
+	items := []Purchase{
+		{
+			ProductID:    "RJ01000000",
+			Title:        "作品名, 上巻",
+			PurchaseDate: time.Date(2023, 7, 1, 0, 0, 0, 0, jst),
+			PricePaid:    1320,
+			Downloadable: true,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "csv",
+			format: "csv",
+			want: "product_id,title,maker,purchase_date,price_paid,downloadable\n" +
+				"RJ01000000,\"作品名, 上巻\",,2023-07-01,1320,true\n",
+		},
+		{name: "xml", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Export(&buf, tt.format, items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Export() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// PurchaseHistoryUrlを設定していないと、ページに移動する前にエラーになることの確認。
+func TestPurchaseHistoryTasksUrlNotSet(t *testing.T) {
+	err := ScrapingTaskManager{}.PurchaseHistoryTasks(&[]Purchase{}).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "PurchaseHistoryUrl") {
+		t.Errorf("PurchaseHistoryTasks() error = %v, want contains %v", err, "PurchaseHistoryUrl")
+	}
+}
+
+// 対応していない拡張子では、すでにあるファイルを消さないことの確認。
+func TestExportFileFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "purchases.xml")
+	if err := os.WriteFile(path, []byte("<keep/>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportFile(path, []Purchase{{ProductID: "RJ01000000"}}); err == nil {
+		t.Errorf("ExportFile() error = %v, want error", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "<keep/>" {
+		t.Errorf("ExportFile() changed the file to %q, %v", b, err)
+	}
+}
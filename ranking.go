@@ -0,0 +1,226 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ランキングの集計期間です。
+const (
+	RankingDay   = "day"
+	RankingWeek  = "week"
+	RankingMonth = "month"
+	RankingYear  = "year"
+	RankingTotal = "total"
+)
+
+// ランキングの1件分です。
+type RankEntry struct {
+	Rank      int
+	ProductID string
+	Title     string
+	Maker     string
+	Price     int
+	Sales     string // 販売数などの売れ行きの表示。表示されたまま。
+}
+
+// ある時点のランキングです。
+type RankingSnapshot struct {
+	Category string
+	Period   string
+	TakenAt  time.Time
+	Entries  []RankEntry
+}
+
+// 前回のランキングからの順位の変化です。
+type RankMove struct {
+	ProductID string
+	Title     string
+	PrevRank  int // 前回の順位。前回ランキングに無ければ0。
+	Rank      int // 今回の順位。今回ランキングから外れたら0。
+	Change    int // 上がった順位の数。下がったら負の数。
+}
+
+// 新しくランキングに入ったか。
+func (m RankMove) New() bool {
+	return m.PrevRank == 0
+}
+
+// ランキングから外れたか。
+func (m RankMove) Dropped() bool {
+	return m.Rank == 0
+}
+
+// ランキングページのurlを返す。
+// base ScrapingTaskManagerのRankingUrl
+// category voice, comicなどの作品形式。空文字なら全体。
+// period RankingDayなどの集計期間
+func RankingURL(base string, category string, period string) string {
+	u := strings.TrimSuffix(base, "/") + "/" + url.PathEscape(period)
+	if category != "" {
+		u += "?category=" + url.QueryEscape(category)
+	}
+	return u
+}
+
+// ランキングページを読んで、順位の順に並べた一覧を取得する。
+// category voice, comicなどの作品形式。空文字なら全体。
+// period RankingDayなどの集計期間
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) RankingTasks(category string, period string, out *[]RankEntry, t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+	if err := requireUrl("RankingUrl", s.RankingUrl); err != nil {
+		return s.ErrorTask(err.Error())
+	}
+
+	fields := map[string]Selector{
+		"Rank":  s.RankingItemRankSel,
+		"Title": s.RankingItemTitleSel,
+		"Maker": s.RankingItemMakerSel,
+		"Price": s.RankingItemPriceSel,
+		"Sales": s.RankingItemSalesSel,
+	}
+	return chromedp.Tasks{
+		s.MovePageTasks(RankingURL(s.RankingUrl, category, period), waitTime),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var items []listItem
+			if err := s.listTasks(s.RankingItemSel, fields, &items).Do(ctx); err != nil {
+				return err
+			}
+			*out = rankEntries(items)
+			return nil
+		}),
+	}
+}
+
+// 一覧ページの各項目をRankEntryにする。
+// 順位は最初の数値だけを読む。"1位 (前日 3位)"なら1。
+// 順位が読めないものは、並び順を順位にする。
+func rankEntries(items []listItem) []RankEntry {
+	var entries []RankEntry
+	for _, item := range items {
+		id := ProductIDFromURL(item["Title"].Href)
+		if id == "" {
+			continue
+		}
+		rank, err := strconv.Atoi(digitsPattern.FindString(item["Rank"].Text))
+		if err != nil {
+			rank = len(entries) + 1
+		}
+		entries = append(entries, RankEntry{
+			Rank:      rank,
+			ProductID: id,
+			Title:     item["Title"].Text,
+			Maker:     item["Maker"].Text,
+			Price:     parsePrice(item["Price"].Text),
+			Sales:     item["Sales"].Text,
+		})
+	}
+	return entries
+}
+
+// スナップショットのファイル名です。
+// 名前順に並べると古い順になる。
+func rankingSnapshotName(category string, period string, takenAt time.Time) string {
+	if category == "" {
+		category = "all"
+	}
+	return fmt.Sprintf("ranking_%s_%s_%s.json", category, period, takenAt.UTC().Format("20060102T150405Z"))
+}
+
+// ランキングのスナップショットをdirにjsonで保存する。
+// 保存したファイルのパスを返す。
+func (s ScrapingTaskManager) SaveRankingSnapshot(dir string, snapshot RankingSnapshot) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("ランキングの保存先を作れませんでした。: %w", err)
+	}
+
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, rankingSnapshotName(snapshot.Category, snapshot.Period, snapshot.TakenAt))
+	if err := os.WriteFile(path, b, 0640); err != nil {
+		return "", fmt.Errorf("ランキングを保存できませんでした。: %w", err)
+	}
+	s.logger().Info("ランキングを保存しました。", "path", path)
+	return path, nil
+}
+
+// 保存したランキングのスナップショットを読み込む。
+func LoadRankingSnapshot(path string) (RankingSnapshot, error) {
+	var snapshot RankingSnapshot
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("ランキングを読み込めませんでした。: %w", err)
+	}
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("ランキングを解釈できませんでした。: %w", err)
+	}
+	return snapshot, nil
+}
+
+// dirに保存した、beforeより前で一番新しいスナップショットを読み込む。
+// 無ければokがfalseになる。
+func LatestRankingSnapshot(dir string, category string, period string, before time.Time) (snapshot RankingSnapshot, ok bool, err error) {
+	if category == "" {
+		category = "all"
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("ranking_%s_%s_*.json", category, period)))
+	if err != nil {
+		return snapshot, false, err
+	}
+	sort.Strings(paths)
+
+	limit := rankingSnapshotName(category, period, before)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if filepath.Base(paths[i]) >= limit {
+			continue
+		}
+		snapshot, err = LoadRankingSnapshot(paths[i])
+		return snapshot, err == nil, err
+	}
+	return snapshot, false, nil
+}
+
+// 前回と今回のランキングを比べて、順位の変化を今回の順位の順に返す。
+// ランキングから外れた作品は最後に前回の順位の順で並ぶ。
+func DiffRanking(prev []RankEntry, cur []RankEntry) []RankMove {
+	prevRanks := map[string]RankEntry{}
+	for _, e := range prev {
+		prevRanks[e.ProductID] = e
+	}
+
+	var moves []RankMove
+	seen := map[string]bool{}
+	for _, e := range cur {
+		seen[e.ProductID] = true
+		m := RankMove{ProductID: e.ProductID, Title: e.Title, Rank: e.Rank}
+		if p, ok := prevRanks[e.ProductID]; ok {
+			m.PrevRank = p.Rank
+			m.Change = p.Rank - e.Rank
+		}
+		moves = append(moves, m)
+	}
+	for _, e := range prev {
+		if !seen[e.ProductID] {
+			moves = append(moves, RankMove{ProductID: e.ProductID, Title: e.Title, PrevRank: e.Rank})
+		}
+	}
+	return moves
+}
@@ -0,0 +1,123 @@
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRankingURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		period   string
+		want     string
+	}{
+		{name: "all", category: "", period: RankingDay, want: "https://www.dlsite.com/maniax/ranking/day"},
+		{name: "voice", category: "voice", period: RankingWeek, want: "https://www.dlsite.com/maniax/ranking/week?category=voice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RankingURL("https://www.dlsite.com/maniax/ranking/", tt.category, tt.period); got != tt.want {
+				t.Errorf("RankingURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 一覧ページの項目がRankEntryになるか確認。
+func TestRankEntries(t *testing.T) {
+	item := func(rank string, id string) listItem {
+		return listItem{
+			"Rank":  {Text: rank},
+			"Title": {Text: id, Href: "https://www.dlsite.com/maniax/work/=/product_id/" + id + ".html"},
+			"Price": {Text: "1,320円"},
+		}
+	}
+	tests := []struct {
+		name string
+		args listItem
+		want int
+	}{
+		{name: "rank", args: item("1位", "RJ01000001"), want: 1},
+		{name: "previous rank", args: item("1位 (前日 3位)", "RJ01000001"), want: 1},
+		{name: "large", args: item("120位", "RJ01000001"), want: 120},
+		{name: "no rank", args: item("", "RJ01000001"), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankEntries([]listItem{tt.args})
+			if len(got) != 1 || got[0].Rank != tt.want || got[0].Price != 1320 {
+				t.Errorf("rankEntries() = %+v, want Rank %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 順位の変化が正しく計算されるか確認。
+func TestDiffRanking(t *testing.T) {
+	prev := []RankEntry{
+		{Rank: 1, ProductID: "RJ01000001", Title: "A"},
+		{Rank: 2, ProductID: "RJ01000002", Title: "B"},
+		{Rank: 3, ProductID: "RJ01000003", Title: "C"},
+	}
+	cur := []RankEntry{
+		{Rank: 1, ProductID: "RJ01000002", Title: "B"},
+		{Rank: 2, ProductID: "RJ01000004", Title: "D"},
+		{Rank: 3, ProductID: "RJ01000001", Title: "A"},
+	}
+	want := []RankMove{
+		{ProductID: "RJ01000002", Title: "B", PrevRank: 2, Rank: 1, Change: 1},
+		{ProductID: "RJ01000004", Title: "D", PrevRank: 0, Rank: 2, Change: 0},
+		{ProductID: "RJ01000001", Title: "A", PrevRank: 1, Rank: 3, Change: -2},
+		{ProductID: "RJ01000003", Title: "C", PrevRank: 3, Rank: 0, Change: 0},
+	}
+	got := DiffRanking(prev, cur)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffRanking() = %+v, want %+v", got, want)
+	}
+	if !got[1].New() || !got[3].Dropped() {
+		t.Errorf("DiffRanking() New, Droppedが正しくありません。 %+v", got)
+	}
+}
+
+// 保存したスナップショットのうち、前回のものが読み込めるか確認。
+func TestLatestRankingSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	first := time.Date(2023, 7, 1, 9, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	for _, takenAt := range []time.Time{first, second} {
+		_, err := ScrapingTaskManager{}.SaveRankingSnapshot(dir, RankingSnapshot{
+			Category: "voice",
+			Period:   RankingDay,
+			TakenAt:  takenAt,
+			Entries:  []RankEntry{{Rank: 1, ProductID: "RJ01000001"}},
+		})
+		if err != nil {
+			t.Fatalf("SaveRankingSnapshot() = %v", err)
+		}
+	}
+
+	got, ok, err := LatestRankingSnapshot(dir, "voice", RankingDay, second)
+	if err != nil || !ok {
+		t.Fatalf("LatestRankingSnapshot() = %v, %v", ok, err)
+	}
+	if !got.TakenAt.Equal(first) {
+		t.Errorf("LatestRankingSnapshot() TakenAt = %v, want %v", got.TakenAt, first)
+	}
+
+	_, ok, err = LatestRankingSnapshot(dir, "voice", RankingDay, first)
+	if err != nil || ok {
+		t.Errorf("LatestRankingSnapshot() = %v, %v, want false", ok, err)
+	}
+}
+
+// RankingUrlを設定していないと、ページに移動する前にエラーになることの確認。
+func TestRankingTasksUrlNotSet(t *testing.T) {
+	err := ScrapingTaskManager{}.RankingTasks("", RankingDay, &[]RankEntry{}).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "RankingUrl") {
+		t.Errorf("RankingTasks() error = %v, want contains %v", err, "RankingUrl")
+	}
+}
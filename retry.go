@@ -0,0 +1,113 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 失敗した処理をやり直す設定です。
+// ScrapingTaskManagerのRetryPolicyに設定すると、MovePageTasks, ClickTasks, TextContentTasksが失敗したときにやり直す。
+type RetryPolicy struct {
+	MaxAttempts int           // 最初の1回を含めた実行回数の上限。1以下ならやり直さない。
+	Backoff     time.Duration // 1回目のやり直しまで待つ時間。0なら1秒。
+	MaxBackoff  time.Duration // 待つ時間の上限。0なら30秒。
+	Multiplier  float64       // やり直すたびに待つ時間を何倍にするか。0なら2。
+	Jitter      float64       // 待つ時間をランダムにずらす割合。0から1。0.2なら±20%。
+	Reload      bool          // やり直す前にページを再読み込みする。
+
+	// やり直すエラーか。nilならretryableError。
+	Retryable func(err error) bool
+}
+
+// 0のところをデフォルトの値にしたもの。
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Backoff == 0 {
+		p.Backoff = time.Second
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+	if p.Retryable == nil {
+		p.Retryable = retryableError
+	}
+	return p
+}
+
+// attempt回目が失敗した後に待つ時間。
+// r 0から1の乱数。Jitterでずらすのに使う。
+func (p RetryPolicy) backoff(attempt int, r float64) time.Duration {
+	d := float64(p.Backoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	d = math.Min(d, float64(p.MaxBackoff))
+	d *= 1 + p.Jitter*(2*r-1)
+	return time.Duration(d)
+}
+
+// やり直せば成功するかもしれないエラーか。
+// ユーザー名やパスワードの間違いなど、やり直しても変わらないものと、キャンセルされたものはやり直さない。
+func retryableError(err error) bool {
+	for _, e := range []error{
+		context.Canceled,
+		ErrAlreadyLoggedIn,
+		ErrInvalidCredentials,
+		ErrCaptchaRequired,
+		ErrTwoFactorRequired,
+		ErrVisualMismatch,
+		ErrDecrypt,
+	} {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// tasksが失敗したら、policyに従って待ってからやり直す。やり直すたびにログを出す。
+// policy nilならScrapingTaskManagerのRetryPolicyを使う。どちらもnilならやり直さない。
+func (s ScrapingTaskManager) RetryTasks(policy *RetryPolicy, tasks chromedp.Action) chromedp.Tasks {
+	if policy == nil {
+		policy = s.RetryPolicy
+	}
+	if policy == nil {
+		return chromedp.Tasks{tasks}
+	}
+	p := policy.withDefaults()
+
+	return chromedp.Tasks{
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			for attempt := 1; ; attempt++ {
+				// 失敗したときのページの状態は、やり直さないと決まってから保存する。
+				actx, capture := deferCapture(withAttempt(ctx, attempt))
+				err := tasks.Do(actx)
+				if err == nil {
+					return nil
+				}
+				// 全体のcontextが終わっていたら、やり直しても失敗する。
+				if attempt >= p.MaxAttempts || ctx.Err() != nil || !p.Retryable(err) {
+					capture.finish(ctx)
+					return err
+				}
+
+				wait := p.backoff(attempt, rand.Float64())
+				s.logger().Warn("やり直します。", "attempt", attempt, "max_attempts", p.MaxAttempts, "wait", wait, "error", err)
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(wait):
+				}
+				if p.Reload {
+					if err := chromedp.Reload().Do(ctx); err != nil {
+						s.logger().Warn("ページを再読み込みできませんでした。", "error", err)
+					}
+				}
+			}
+		}),
+	}
+}
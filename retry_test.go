@@ -0,0 +1,79 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// やり直すたびに待つ時間が伸びて、上限で止まるか確認。
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second, Jitter: 0.5}.withDefaults()
+	tests := []struct {
+		name    string
+		attempt int
+		r       float64
+		want    time.Duration
+	}{
+		{name: "first", attempt: 1, r: 0.5, want: time.Second},
+		{name: "second", attempt: 2, r: 0.5, want: 2 * time.Second},
+		{name: "third", attempt: 3, r: 0.5, want: 4 * time.Second},
+		{name: "max", attempt: 4, r: 0.5, want: 5 * time.Second},
+		{name: "jitter min", attempt: 1, r: 0, want: 500 * time.Millisecond},
+		{name: "jitter max", attempt: 1, r: 1, want: 1500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.backoff(tt.attempt, tt.r); got != tt.want {
+				t.Errorf("RetryPolicy.backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 失敗したらやり直し、やり直しても変わらないエラーではやり直さないことの確認。
+func TestRetryTasks(t *testing.T) {
+	errFlaky := errors.New("Could not find node with given id")
+	tests := []struct {
+		name     string
+		policy   *RetryPolicy
+		errs     []error // 1回目から順に返すエラー
+		want     error
+		attempts []int
+	}{
+		{name: "success", policy: &RetryPolicy{MaxAttempts: 3}, errs: []error{nil}, attempts: []int{1}},
+		{name: "success after retry", policy: &RetryPolicy{MaxAttempts: 3}, errs: []error{errFlaky, errFlaky, nil}, attempts: []int{1, 2, 3}},
+		{name: "max attempts", policy: &RetryPolicy{MaxAttempts: 2}, errs: []error{errFlaky, errFlaky, nil}, want: errFlaky, attempts: []int{1, 2}},
+		{name: "not retryable", policy: &RetryPolicy{MaxAttempts: 3}, errs: []error{ErrInvalidCredentials, nil}, want: ErrInvalidCredentials, attempts: []int{1}},
+		{name: "no policy", errs: []error{errFlaky, nil}, want: errFlaky, attempts: []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.policy != nil {
+				tt.policy.Backoff = time.Millisecond
+			}
+			s := ScrapingTaskManager{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			var attempts []int
+			action := chromedp.ActionFunc(func(ctx context.Context) error {
+				attempts = append(attempts, attemptFrom(ctx))
+				return tt.errs[len(attempts)-1]
+			})
+			if err := s.RetryTasks(tt.policy, action).Do(context.Background()); err != tt.want {
+				t.Errorf("RetryTasks() error = %v, want %v", err, tt.want)
+			}
+			if len(attempts) != len(tt.attempts) {
+				t.Fatalf("RetryTasks() attempts = %v, want %v", attempts, tt.attempts)
+			}
+			for i := range attempts {
+				if attempts[i] != tt.attempts[i] {
+					t.Errorf("RetryTasks() attempts = %v, want %v", attempts, tt.attempts)
+				}
+			}
+		})
+	}
+}
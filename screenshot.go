@@ -0,0 +1,120 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// 要素のページ上の位置と大きさを返すjs。スクロールしていても良いようにスクロール量を足す。
+const elementRectJS = `function() {
+	const r = this.getBoundingClientRect();
+	return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height};
+}`
+
+// pdfはchromeの印刷の機能で作るので、ヘッドレスモードでしか保存できない。
+var ErrPDFRequiresHeadless = errors.New("pdfはヘッドレスモードでしか保存できません。")
+
+// ファイル名の拡張子からスクリーンショットの形式を決める。
+// png, jpeg, webp, pdfのどれかを返す。
+func screenshotFormat(fileName string) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	switch ext {
+	case "png", "webp", "pdf":
+		return ext, nil
+	case "jpg", "jpeg":
+		return "jpeg", nil
+	}
+	return "", fmt.Errorf("スクリーンショットの形式に対応していません。png, jpg, jpeg, webp, pdfのどれかにしてください。: %s", fileName)
+}
+
+// スクリーンショットを撮る。
+// sel nilか空文字ならページ全体。要素を指定したら、その要素が見えるまでスクロールしてから撮る。
+// format screenshotFormatで決めた形式。pdfは常にページ全体になる。ヘッドレスモードでなければErrPDFRequiresHeadless。
+func (s ScrapingTaskManager) screenshot(ctx context.Context, sel interface{}, format string) ([]byte, error) {
+	if format == "pdf" {
+		// ヘッドレスモードでないとPrintToPDFはわかりにくいエラーになるので、先に確かめる。
+		_, _, _, userAgent, _, err := browser.GetVersion().Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(userAgent, "Headless") {
+			return nil, ErrPDFRequiresHeadless
+		}
+		buf, _, err := page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+		return buf, err
+	}
+
+	capture := page.CaptureScreenshot().
+		WithFormat(page.CaptureScreenshotFormat(format)).
+		WithCaptureBeyondViewport(true).
+		WithFromSurface(true)
+	if format != "png" {
+		quality := s.ScreenShotQuality
+		if quality == 0 {
+			quality = 90
+		}
+		capture = capture.WithQuality(int64(quality))
+	}
+	if sel == nil || sel == "" {
+		return capture.Do(ctx)
+	}
+
+	q, opts := s.query(sel)
+	clip, err := elementClip(ctx, q, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return capture.WithClip(clip).Do(ctx)
+}
+
+// 要素が見えるまでスクロールして、スクリーンショットで切り抜く範囲を返す。
+// opts Selectorの解釈の仕方。指定しない場合はByQuery。
+func elementClip(ctx context.Context, sel interface{}, opts ...chromedp.QueryOption) (*page.Viewport, error) {
+	opts = append([]chromedp.QueryOption{chromedp.ByQuery}, opts...)
+	var clip page.Viewport
+	err := chromedp.Tasks{
+		chromedp.ScrollIntoView(sel, opts...),
+		chromedp.QueryAfter(sel, func(ctx context.Context, _ runtime.ExecutionContextID, nodes ...*cdp.Node) error {
+			if len(nodes) == 0 {
+				return fmt.Errorf("要素が見つかりませんでした。: %v", sel)
+			}
+			obj, err := dom.ResolveNode().WithNodeID(nodes[0].NodeID).Do(ctx)
+			if err != nil {
+				return err
+			}
+			res, exception, err := runtime.CallFunctionOn(elementRectJS).
+				WithObjectID(obj.ObjectID).
+				WithReturnByValue(true).
+				Do(ctx)
+			if err != nil {
+				return err
+			}
+			if exception != nil {
+				return exception
+			}
+			return json.Unmarshal(res.Value, &clip)
+		}, append(opts, chromedp.NodeVisible)...),
+	}.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 端数があると画像がずれるので、整数にそろえる。
+	x, y := math.Round(clip.X), math.Round(clip.Y)
+	clip.Width, clip.Height = math.Round(clip.Width+clip.X-x), math.Round(clip.Height+clip.Y-y)
+	clip.X, clip.Y = x, y
+	clip.Scale = 1
+	return &clip, nil
+}
@@ -0,0 +1,32 @@
+package tasks
+
+import "testing"
+
+// 拡張子からスクリーンショットの形式が決まるか確認。
+func TestScreenshotFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    string
+		wantErr bool
+	}{
+		{name: "png", args: "logs/login.png", want: "png"},
+		{name: "jpg", args: "logs/login.jpg", want: "jpeg"},
+		{name: "jpeg", args: "logs/login.JPEG", want: "jpeg"},
+		{name: "webp", args: "logs/login.webp", want: "webp"},
+		{name: "pdf", args: "logs/login.pdf", want: "pdf"},
+		{name: "gif", args: "logs/login.gif", wantErr: true},
+		{name: "none", args: "logs/login", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := screenshotFormat(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("screenshotFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("screenshotFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package tasks
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 検索結果の並び順です。
+const (
+	SearchOrderTrend       = "trend"     // 人気順
+	SearchOrderReleaseDesc = "release_d" // 新しい順
+	SearchOrderRelease     = "release"   // 古い順
+	SearchOrderDownload    = "dl_d"      // 販売数順
+	SearchOrderPrice       = "price"     // 安い順
+	SearchOrderPriceDesc   = "price_d"   // 高い順
+	SearchOrderRating      = "rate_d"    // 評価順
+	SearchOrderReview      = "review_d"  // レビュー数順
+)
+
+// 検索の条件です。ゼロ値の条件は使わない。
+type SearchQuery struct {
+	Keyword     string
+	Genres      []string // ジャンルのID。複数指定するといずれかに合致するもの。
+	Circle      string   // サークル名、メーカー名
+	PriceLow    int
+	PriceHigh   int
+	ReleaseFrom time.Time
+	ReleaseTo   time.Time
+	Order       string // SearchOrder*
+	MaxPages    int    // 辿るページ数の上限。0なら最後のページまで辿る。
+}
+
+// 検索結果などの一覧ページの1件分です。
+type WorkSummary struct {
+	ProductID string
+	Title     string
+	Price     int
+	Rating    float64 // 評価の星。0から5。
+	Url       string
+}
+
+// 検索結果の1ページ目のurlを返す。
+// base ScrapingTaskManagerのSearchUrl
+func (q SearchQuery) URL(base string) string {
+	var path []string
+	add := func(key string, value string) {
+		path = append(path, key, url.PathEscape(value))
+	}
+
+	add("language", "jp")
+	if q.Keyword != "" {
+		add("keyword", q.Keyword)
+	}
+	for i, genre := range q.Genres {
+		add("genre["+strconv.Itoa(i)+"]", genre)
+	}
+	if len(q.Genres) > 1 {
+		add("genre_and_or", "or")
+	}
+	if q.Circle != "" {
+		add("keyword_maker_name", q.Circle)
+	}
+	if q.PriceLow > 0 {
+		add("price_low", strconv.Itoa(q.PriceLow))
+	}
+	if q.PriceHigh > 0 {
+		add("price_high", strconv.Itoa(q.PriceHigh))
+	}
+	if !q.ReleaseFrom.IsZero() {
+		add("regist_date_start", q.ReleaseFrom.Format("2006-01-02"))
+	}
+	if !q.ReleaseTo.IsZero() {
+		add("regist_date_end", q.ReleaseTo.Format("2006-01-02"))
+	}
+	order := q.Order
+	if order == "" {
+		order = SearchOrderTrend
+	}
+	add("order", order)
+	add("options_and_or", "and")
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.Join(path, "/") + "/"
+}
+
+// 検索結果のページを辿って、作品の一覧を取得する。
+// 次のページへのリンクが無くなるか、query.MaxPagesに達したら終わる。
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) SearchTasks(query SearchQuery, out *[]WorkSummary, t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+	if err := requireUrl("SearchUrl", s.SearchUrl); err != nil {
+		return s.ErrorTask(err.Error())
+	}
+
+	fields := map[string]Selector{
+		"Title":  s.SearchItemTitleSel,
+		"Price":  s.SearchItemPriceSel,
+		"Rating": s.SearchItemRatingSel,
+	}
+	return chromedp.Tasks{
+		s.MovePageTasks(query.URL(s.SearchUrl), waitTime),
+		s.paginateTasks(s.SearchNextSel, query.MaxPages, waitTime, func(ctx context.Context) error {
+			var items []listItem
+			if err := s.listTasks(s.SearchItemSel, fields, &items).Do(ctx); err != nil {
+				return err
+			}
+			*out = append(*out, workSummaries(items)...)
+			return nil
+		}),
+	}
+}
+
+// 一覧ページの各項目をWorkSummaryにする。作品IDが無いものは飛ばす。
+func workSummaries(items []listItem) []WorkSummary {
+	var summaries []WorkSummary
+	for _, item := range items {
+		id := ProductIDFromURL(item["Title"].Href)
+		if id == "" {
+			continue
+		}
+		summaries = append(summaries, WorkSummary{
+			ProductID: id,
+			Title:     item["Title"].Text,
+			Price:     parsePrice(item["Price"].Text),
+			Rating:    parseRating(item["Rating"]),
+			Url:       item["Title"].Href,
+		})
+	}
+	return summaries
+}
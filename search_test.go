@@ -0,0 +1,86 @@
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 検索条件からurlが組み立てられるか確認。
+func TestSearchQueryURL(t *testing.T) {
+	tests := []struct {
+		name string
+		args SearchQuery
+		want string
+	}{
+		{
+			name: "empty",
+			args: SearchQuery{},
+			want: "https://www.dlsite.com/maniax/fsr/=/language/jp/order/trend/options_and_or/and/",
+		},
+		{
+			name: "all",
+			args: SearchQuery{
+				Keyword:     "耳かき ASMR",
+				Genres:      []string{"497", "060"},
+				Circle:      "サークル",
+				PriceLow:    500,
+				PriceHigh:   1500,
+				ReleaseFrom: time.Date(2023, 1, 1, 0, 0, 0, 0, jst),
+				ReleaseTo:   time.Date(2023, 6, 30, 0, 0, 0, 0, jst),
+				Order:       SearchOrderReleaseDesc,
+			},
+			want: "https://www.dlsite.com/maniax/fsr/=/language/jp" +
+				"/keyword/%E8%80%B3%E3%81%8B%E3%81%8D%20ASMR" +
+				"/genre[0]/497/genre[1]/060/genre_and_or/or" +
+				"/keyword_maker_name/%E3%82%B5%E3%83%BC%E3%82%AF%E3%83%AB" +
+				"/price_low/500/price_high/1500" +
+				"/regist_date_start/2023-01-01/regist_date_end/2023-06-30" +
+				"/order/release_d/options_and_or/and/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.args.URL("https://www.dlsite.com/maniax/fsr/="); got != tt.want {
+				t.Errorf("SearchQuery.URL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 一覧ページの項目がWorkSummaryになるか確認。
+func TestWorkSummaries(t *testing.T) {
+	items := []listItem{
+		{
+			"Title":  {Text: "作品名", Href: "https://www.dlsite.com/maniax/work/=/product_id/RJ01000000.html"},
+			"Price":  {Text: "1,320円"},
+			"Rating": {Class: "star_rating star_45 mini"},
+		},
+		// 広告など作品でないもの
+		{
+			"Title": {Text: "特集", Href: "https://www.dlsite.com/maniax/campaign"},
+		},
+	}
+	want := []WorkSummary{
+		{
+			ProductID: "RJ01000000",
+			Title:     "作品名",
+			Price:     1320,
+			Rating:    4.5,
+			Url:       "https://www.dlsite.com/maniax/work/=/product_id/RJ01000000.html",
+		},
+	}
+	if got := workSummaries(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("workSummaries() = %+v, want %+v", got, want)
+	}
+}
+
+// SearchUrlを設定していないと、ページに移動する前にエラーになることの確認。
+func TestSearchTasksUrlNotSet(t *testing.T) {
+	err := ScrapingTaskManager{}.SearchTasks(SearchQuery{}, &[]WorkSummary{}).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "SearchUrl") {
+		t.Errorf("SearchTasks() error = %v, want contains %v", err, "SearchUrl")
+	}
+}
@@ -0,0 +1,183 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Selectorの解釈の仕方です。
+type QueryType string
+
+const (
+	QueryDefault QueryType = ""       // 指定なし。chromedpのデフォルトのBySearch。jsで探すときはCSS。
+	QueryCSS     QueryType = "css"    // CSSのSelector。chromedp.ByQuery。
+	QueryXPath   QueryType = "xpath"  // XPath。
+	QueryID      QueryType = "id"     // 要素のid。chromedp.ByID。
+	QueryJSPath  QueryType = "jspath" // 要素を返すjsの式。chromedp.ByJSPath。
+	QuerySearch  QueryType = "search" // devtoolsの検索と同じ。CSSでもXPathでも良い。chromedp.BySearch。
+	QueryText    QueryType = "text"   // 表示されている文字列を含む一番内側の要素。classが変わっても使える。
+)
+
+var queryTypes = []QueryType{QueryCSS, QueryXPath, QueryID, QueryJSPath, QuerySearch, QueryText}
+
+// 解釈の仕方を前につけたSelectorの文字列です。
+// "xpath=//h1", "text=年齢確認 はい"のように書く。前につけていなければQueryDefaultになる。
+// ScrapingTaskManagerの*Selや設定ファイルは、この書き方で指定する。
+type Selector string
+
+// 解釈の仕方と式からSelectorを作る。
+func NewSelector(by QueryType, expr string) Selector {
+	if by == QueryDefault {
+		return Selector(expr)
+	}
+	return Selector(string(by) + "=" + expr)
+}
+
+// 解釈の仕方と式に分ける。
+// css=, xpath=, id=, jspath=, search=, text=のどれでも始まらなければ、全体をQueryDefaultの式にする。
+func (sel Selector) split() (QueryType, string) {
+	for _, by := range queryTypes {
+		if expr, ok := strings.CutPrefix(string(sel), string(by)+"="); ok {
+			return by, expr
+		}
+	}
+	return QueryDefault, string(sel)
+}
+
+// 解釈の仕方。
+func (sel Selector) By() QueryType {
+	by, _ := sel.split()
+	return by
+}
+
+// 前につけた解釈の仕方を除いた式。
+func (sel Selector) Expr() string {
+	_, expr := sel.split()
+	return expr
+}
+
+// chromedpに渡す式とQueryOption。
+func (sel Selector) query() (string, []chromedp.QueryOption) {
+	by, expr := sel.split()
+	switch by {
+	case QueryCSS:
+		return expr, []chromedp.QueryOption{chromedp.ByQuery}
+	case QueryXPath, QuerySearch:
+		return expr, []chromedp.QueryOption{chromedp.BySearch}
+	case QueryID:
+		return expr, []chromedp.QueryOption{chromedp.ByID}
+	case QueryJSPath:
+		return expr, []chromedp.QueryOption{chromedp.ByJSPath}
+	case QueryText:
+		return textXPath(expr), []chromedp.QueryOption{chromedp.BySearch}
+	}
+	return expr, nil
+}
+
+// 文字列を含む一番内側の要素を探すXPath。空白の違いは無視する。
+func textXPath(text string) string {
+	lit := xpathLiteral(strings.Join(strings.Fields(text), " "))
+	return fmt.Sprintf(".//*[contains(normalize-space(.), %s)][not(.//*[contains(normalize-space(.), %s)])]", lit, lit)
+}
+
+// XPathの文字列リテラル。XPathにはエスケープが無いので、"と'の両方を含む場合はconcatでつなぐ。
+func xpathLiteral(v string) string {
+	if !strings.Contains(v, `"`) {
+		return `"` + v + `"`
+	}
+	if !strings.Contains(v, "'") {
+		return "'" + v + "'"
+	}
+	parts := strings.Split(v, `"`)
+	for i, p := range parts {
+		parts[i] = `"` + p + `"`
+	}
+	return "concat(" + strings.Join(parts, `, '"', `) + ")"
+}
+
+// chromedpに渡す式とQueryOption。selが文字列ならSelectorとして解釈する。
+// WithQueryTypeで指定したものより、Selectorで指定したものが優先される。
+func (s ScrapingTaskManager) query(sel interface{}) (interface{}, []chromedp.QueryOption) {
+	return selectorQuery(sel, s.queryOptions...)
+}
+
+// chromedpに渡す式と、optsの後にSelectorの解釈の仕方を足したQueryOption。
+func selectorQuery(sel interface{}, opts ...chromedp.QueryOption) (interface{}, []chromedp.QueryOption) {
+	opts = append([]chromedp.QueryOption(nil), opts...)
+	if v, ok := sel.(string); ok {
+		sel = Selector(v)
+	}
+	if v, ok := sel.(Selector); ok {
+		expr, qopts := v.query()
+		return expr, append(opts, qopts...)
+	}
+	return sel, opts
+}
+
+// ページの中でSelectorの要素を探すjsの関数。
+// findAll(sel, root) rootの中でselに合致する要素の配列を返す。rootを省略したらdocument。
+// find(sel, root) 最初の1つを返す。無ければnull。
+// selはSelectorと同じ書き方。前に何もつけていなければCSS。空ならどれにも合致しない。
+// search=はCSSかXPathとして探す。文字列で探すときはtext=をつける。
+const selectorScript = `const findAll = (sel, root = document) => {
+	const m = /^(css|xpath|id|jspath|search|text)=([\s\S]*)$/.exec(sel);
+	const by = m ? m[1] : "css";
+	const expr = m ? m[2] : sel;
+	if (!expr) {
+		return [];
+	}
+	const norm = (v) => v.replace(/\s+/g, " ").trim();
+	const xpath = (x) => {
+		const r = document.evaluate(x, root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+		const nodes = [];
+		for (let i = 0; i < r.snapshotLength; i++) {
+			nodes.push(r.snapshotItem(i));
+		}
+		return nodes;
+	};
+	switch (by) {
+	case "xpath":
+		return xpath(expr);
+	case "id": {
+		const e = document.getElementById(expr);
+		return e && root.contains(e) ? [e] : [];
+	}
+	case "jspath": {
+		const e = (0, eval)(expr);
+		return e ? [e] : [];
+	}
+	case "text": {
+		const t = norm(expr);
+		return Array.from(root.querySelectorAll("*")).filter((e) =>
+			norm(e.textContent).includes(t) && !Array.from(e.children).some((c) => norm(c.textContent).includes(t)));
+	}
+	case "search":
+		try {
+			return Array.from(root.querySelectorAll(expr));
+		} catch (e) {}
+		try {
+			return xpath(expr);
+		} catch (e) {}
+		return [];
+	}
+	return Array.from(root.querySelectorAll(expr));
+};
+const find = (sel, root = document) => findAll(sel, root)[0] || null;
+`
+
+// scriptの前にselectorScriptをつけて、findAllとfindを使えるようにする。
+// script 値を返す式。
+func withSelectorScript(script string) string {
+	return "(() => {\n" + selectorScript + "return " + script + ";\n})()"
+}
+
+// 前に何もつけていない文字列を、chromedpのデフォルトと同じくsearch=にする。
+// findAllは前に何もつけていなければCSSとして探すので、ClickTasksなどと同じ探し方にしたいときに使う。空文字はそのまま。
+func searchSelector(sel Selector) Selector {
+	if by, expr := sel.split(); by == QueryDefault && expr != "" {
+		return NewSelector(QuerySearch, expr)
+	}
+	return sel
+}
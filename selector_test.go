@@ -0,0 +1,119 @@
+package tasks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// 前につけた解釈の仕方と式に分けられるか確認。
+func TestSelectorSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     Selector
+		wantBy   QueryType
+		wantExpr string
+	}{
+		{name: "default", args: "#top_header", wantExpr: "#top_header"},
+		{name: "attribute", args: `a[href="x=1"]`, wantExpr: `a[href="x=1"]`},
+		{name: "css", args: "css=div.btn_yes > a", wantBy: QueryCSS, wantExpr: "div.btn_yes > a"},
+		{name: "xpath", args: "xpath=//h1[@id='work_name']", wantBy: QueryXPath, wantExpr: "//h1[@id='work_name']"},
+		{name: "id", args: "id=work_name", wantBy: QueryID, wantExpr: "work_name"},
+		{name: "jspath", args: "jspath=document.body", wantBy: QueryJSPath, wantExpr: "document.body"},
+		{name: "search", args: "search=はい", wantBy: QuerySearch, wantExpr: "はい"},
+		{name: "text", args: "text=年齢確認 はい", wantBy: QueryText, wantExpr: "年齢確認 はい"},
+		{name: "unknown", args: "role=button", wantExpr: "role=button"},
+		{name: "empty", args: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.args.By(); got != tt.wantBy {
+				t.Errorf("Selector.By() = %v, want %v", got, tt.wantBy)
+			}
+			if got := tt.args.Expr(); got != tt.wantExpr {
+				t.Errorf("Selector.Expr() = %v, want %v", got, tt.wantExpr)
+			}
+			if got := NewSelector(tt.wantBy, tt.wantExpr); got != tt.args {
+				t.Errorf("NewSelector() = %v, want %v", got, tt.args)
+			}
+		})
+	}
+}
+
+// 設定ファイルに文字列で書けるか確認。
+func TestSelectorUnmarshal(t *testing.T) {
+	var got struct{ Button Selector }
+	if err := json.Unmarshal([]byte(`{"Button": "text=はい"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Button.By() != QueryText || got.Button.Expr() != "はい" {
+		t.Errorf("json.Unmarshal() = %v, want %v", got.Button, NewSelector(QueryText, "はい"))
+	}
+}
+
+// XPathの文字列リテラルになるか確認。
+func TestXPathLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{name: "plain", args: "はい", want: `"はい"`},
+		{name: "double quote", args: `"はい"`, want: `'"はい"'`},
+		{name: "both", args: `it's "ok"`, want: `concat("it's ", '"', "ok", '"', "")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := xpathLiteral(tt.args); got != tt.want {
+				t.Errorf("xpathLiteral() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// chromedpに渡す式とQueryOptionになるか確認。
+func TestSelectorQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     interface{}
+		want     interface{}
+		wantOpts int
+	}{
+		{name: "default", args: "#top_header", want: "#top_header", wantOpts: 0},
+		{name: "css", args: "css=#top_header", want: "#top_header", wantOpts: 1},
+		{name: "selector", args: NewSelector(QueryID, "work_name"), want: "work_name", wantOpts: 1},
+		{name: "text", args: "text=はい", want: textXPath("はい"), wantOpts: 1},
+		{name: "nil", args: nil, want: nil, wantOpts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, opts := selectorQuery(tt.args)
+			if got != tt.want {
+				t.Errorf("selectorQuery() = %v, want %v", got, tt.want)
+			}
+			if len(opts) != tt.wantOpts {
+				t.Errorf("selectorQuery() opts = %v, want %v", len(opts), tt.wantOpts)
+			}
+		})
+	}
+}
+
+// 前に何もつけていない文字列だけsearch=になるか確認。
+func TestSearchSelector(t *testing.T) {
+	tests := []struct {
+		name string
+		args Selector
+		want Selector
+	}{
+		{name: "default", args: "#top_header", want: "search=#top_header"},
+		{name: "css", args: "css=#top_header", want: "css=#top_header"},
+		{name: "text", args: "text=はい", want: "text=はい"},
+		{name: "empty", args: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchSelector(tt.args); got != tt.want {
+				t.Errorf("searchSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
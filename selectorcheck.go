@@ -0,0 +1,245 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Selectorに合致するはずの要素の数です。
+type selectorWant int
+
+const (
+	wantOne      selectorWant = iota // ちょうど1つ
+	wantSome                         // 1つ以上。一覧の項目など。
+	wantOptional                     // 0か1つ。セール中やエラーのときだけ表示されるものなど。
+)
+
+// Selectorを調べた結果です。
+const (
+	SelectorOK        = "ok"
+	SelectorMissing   = "missing"   // 見つからない
+	SelectorAmbiguous = "ambiguous" // 1つのはずが複数見つかった
+)
+
+// Selectorを1つ調べた結果です。
+type SelectorReport struct {
+	Page     string // 調べたページ。top, login, workなど。
+	Url      string
+	Field    string // ScrapingTaskManagerのフィールド名
+	Selector Selector
+	Within   Selector `json:",omitempty"` // 一覧の1件の中で数えた場合は、その1件のSelector
+	Count    int      // 合致した要素の数
+	Status   string   // SelectorOKなど
+}
+
+// 調べるSelectorです。
+type selectorCheck struct {
+	field  string
+	sel    Selector
+	want   selectorWant
+	within Selector // 空でなければ、この要素の最初の1つの中で数える
+}
+
+// 調べるページです。
+type selectorPage struct {
+	name   string
+	url    string         // 空なら移動しない
+	before chromedp.Tasks // 調べる前にすること
+	checks []selectorCheck
+}
+
+// 合致した数から結果を決める。
+func selectorStatus(count int, want selectorWant) string {
+	switch {
+	case count == 0 && want != wantOptional:
+		return SelectorMissing
+	case count > 1 && want != wantSome:
+		return SelectorAmbiguous
+	}
+	return SelectorOK
+}
+
+// 設定したSelectorを調べるページの一覧。
+// productID 作品ページを調べるときの作品ID。空なら作品ページは調べない。
+func (s ScrapingTaskManager) selectorPages(productID string, waitTime time.Duration) []selectorPage {
+	var workUrl string
+	if productID != "" && s.WorkUrl != "" {
+		u, err := s.workURL(productID)
+		if err != nil {
+			s.logger().Warn("作品ページは調べません。", "error", err)
+		}
+		workUrl = u
+	}
+	// 年齢認証のページが無ければ、ボタンを押した後のページも調べない。
+	var ageNext chromedp.Tasks
+	if s.AgePermissionUrl != "" && s.AgePermissionSel != "" {
+		ageNext = chromedp.Tasks{
+			// すでに年齢認証を通っていてボタンが無ければ、押さずにそのまま調べる。
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				var count int
+				if err := s.CountTasks(s.AgePermissionSel, &count).Do(ctx); err != nil {
+					return err
+				}
+				if count == 0 {
+					return nil
+				}
+				return s.ClickTasks(s.AgePermissionSel, waitTime).Do(ctx)
+			}),
+		}
+	}
+	var searchUrl, rankingUrl string
+	if s.SearchUrl != "" {
+		searchUrl = SearchQuery{}.URL(s.SearchUrl)
+	}
+	if s.RankingUrl != "" {
+		rankingUrl = RankingURL(s.RankingUrl, "", RankingDay)
+	}
+
+	return []selectorPage{
+		{
+			name: "age-gate",
+			url:  s.AgePermissionUrl,
+			checks: []selectorCheck{
+				{field: "AgePermissionSel", sel: s.AgePermissionSel, want: wantOne},
+			},
+		},
+		{
+			// 年齢認証のボタンを押した後のページ。
+			name:   "top",
+			before: ageNext,
+			checks: []selectorCheck{
+				{field: "AgePermissionNextSel", sel: s.AgePermissionNextSel, want: wantOne},
+			},
+		},
+		{
+			name: "login",
+			url:  s.LogInUrl,
+			checks: []selectorCheck{
+				{field: "LoginUsernameSel", sel: s.LoginUsernameSel, want: wantOne},
+				{field: "LoginPasswordSel", sel: s.LoginPasswordSel, want: wantOne},
+				{field: "LoginButtonSel", sel: s.LoginButtonSel, want: wantOne},
+				{field: "LoginErrorSel", sel: s.LoginErrorSel, want: wantOptional},
+				{field: "LoginCaptchaSel", sel: s.LoginCaptchaSel, want: wantOptional},
+				{field: "LoginTwoFactorSel", sel: s.LoginTwoFactorSel, want: wantOptional},
+				{field: "LoginOTPButtonSel", sel: s.LoginOTPButtonSel, want: wantOptional},
+			},
+		},
+		{
+			name: "work",
+			url:  workUrl,
+			checks: []selectorCheck{
+				{field: "WorkTitleSel", sel: s.WorkTitleSel, want: wantOne},
+				{field: "WorkMakerSel", sel: s.WorkMakerSel, want: wantOne},
+				{field: "WorkPriceSel", sel: s.WorkPriceSel, want: wantOne},
+				{field: "WorkRegularPriceSel", sel: s.WorkRegularPriceSel, want: wantOptional},
+				{field: "WorkPointSel", sel: s.WorkPointSel, want: wantOptional},
+				{field: "WorkOutlineSel", sel: s.WorkOutlineSel, want: wantSome},
+				{field: "WorkGenreSel", sel: s.WorkGenreSel, want: wantSome},
+				{field: "WorkSampleImageSel", sel: s.WorkSampleImageSel, want: wantSome},
+				{field: "WorkDescriptionSel", sel: s.WorkDescriptionSel, want: wantOne},
+			},
+		},
+		{
+			name: "search",
+			url:  searchUrl,
+			checks: []selectorCheck{
+				{field: "SearchItemSel", sel: s.SearchItemSel, want: wantSome},
+				{field: "SearchItemTitleSel", sel: s.SearchItemTitleSel, want: wantOne, within: s.SearchItemSel},
+				{field: "SearchItemPriceSel", sel: s.SearchItemPriceSel, want: wantOne, within: s.SearchItemSel},
+				{field: "SearchItemRatingSel", sel: s.SearchItemRatingSel, want: wantOptional, within: s.SearchItemSel},
+				{field: "SearchNextSel", sel: s.SearchNextSel, want: wantOne},
+			},
+		},
+		{
+			name: "ranking",
+			url:  rankingUrl,
+			checks: []selectorCheck{
+				{field: "RankingItemSel", sel: s.RankingItemSel, want: wantSome},
+				{field: "RankingItemRankSel", sel: s.RankingItemRankSel, want: wantOne, within: s.RankingItemSel},
+				{field: "RankingItemTitleSel", sel: s.RankingItemTitleSel, want: wantOne, within: s.RankingItemSel},
+				{field: "RankingItemMakerSel", sel: s.RankingItemMakerSel, want: wantOne, within: s.RankingItemSel},
+				{field: "RankingItemPriceSel", sel: s.RankingItemPriceSel, want: wantOne, within: s.RankingItemSel},
+				{field: "RankingItemSalesSel", sel: s.RankingItemSalesSel, want: wantOptional, within: s.RankingItemSel},
+			},
+		},
+	}
+}
+
+// 設定したSelectorが、各ページでいくつの要素に合致するか調べる。
+// 年齢認証、トップ、ログイン、作品、検索結果、ランキングのページを順に開く。
+// ログインが必要なページ(購入履歴、お気に入り)は調べない。ログインしていない状態で実行すること。
+// 1つのはずのSelectorが見つからないか複数見つかったものは、StatusがSelectorMissingかSelectorAmbiguousになる。
+// 設定していないSelectorと、urlを設定していないページは調べない。
+// productID 作品ページを調べるときの作品ID。空なら作品ページは調べない。
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) CheckSelectorsTasks(productID string, out *[]SelectorReport, t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+
+	var actions chromedp.Tasks
+	for _, page := range s.selectorPages(productID, waitTime) {
+		page := page
+		if page.url != "" {
+			actions = append(actions, s.MovePageTasks(page.url, waitTime))
+		} else if page.before == nil {
+			continue
+		}
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			if page.before != nil {
+				// 前のページで見つからなかった場合など、できなくても調べ続ける。
+				if err := page.before.Do(ctx); err != nil {
+					s.logger().Warn("ページを調べる準備ができませんでした。", "page", page.name, "error", err)
+				}
+			}
+			var href string
+			if err := s.LocationHrefTasks(&href).Do(ctx); err != nil {
+				return err
+			}
+			for _, c := range page.checks {
+				if c.sel == "" {
+					continue
+				}
+				count, err := s.countSelector(ctx, c)
+				if err != nil {
+					return err
+				}
+				report := SelectorReport{
+					Page:     page.name,
+					Url:      href,
+					Field:    c.field,
+					Selector: c.sel,
+					Within:   c.within,
+					Count:    count,
+					Status:   selectorStatus(count, c.want),
+				}
+				if report.Status != SelectorOK {
+					s.logger().Warn("Selectorが合っていません。", "page", page.name, "field", c.field, "selector", c.sel, "count", count, "status", report.Status)
+				}
+				*out = append(*out, report)
+			}
+			return nil
+		}))
+	}
+	return actions
+}
+
+// Selectorに合致する要素を数える。withinがあれば、その最初の1つの中で数える。
+func (s ScrapingTaskManager) countSelector(ctx context.Context, c selectorCheck) (int, error) {
+	if c.within == "" {
+		var count int
+		err := s.CountTasks(c.sel, &count).Do(ctx)
+		return count, err
+	}
+	var count int
+	err := s.stepAction(step{Task: "Count", Sel: c.sel, Failed: "要素の数を数えられませんでした。"}, func(ctx context.Context) error {
+		return chromedp.Evaluate(withSelectorScript(fmt.Sprintf(`((item) => item ? findAll(%q, item).length : 0)(find(%q))`, searchSelector(c.sel), searchSelector(c.within))), &count).Do(ctx)
+	}).Do(ctx)
+	return count, err
+}
@@ -0,0 +1,28 @@
+package tasks
+
+import "testing"
+
+// 合致した数と期待する数から結果が決まるか確認。
+func TestSelectorStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  selectorWant
+		wantS string
+	}{
+		{name: "one", count: 1, want: wantOne, wantS: SelectorOK},
+		{name: "one missing", count: 0, want: wantOne, wantS: SelectorMissing},
+		{name: "one ambiguous", count: 2, want: wantOne, wantS: SelectorAmbiguous},
+		{name: "some", count: 30, want: wantSome, wantS: SelectorOK},
+		{name: "some missing", count: 0, want: wantSome, wantS: SelectorMissing},
+		{name: "optional", count: 0, want: wantOptional, wantS: SelectorOK},
+		{name: "optional ambiguous", count: 2, want: wantOptional, wantS: SelectorAmbiguous},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectorStatus(tt.count, tt.want); got != tt.wantS {
+				t.Errorf("selectorStatus() = %v, want %v", got, tt.wantS)
+			}
+		})
+	}
+}
@@ -4,16 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	// "cloud.google.com/go/logging"
-
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
-	// "google.golang.org/api/option"
 )
 
 // OpenLogのCallBack関数です。このフォーマット通りに動きます。
@@ -33,14 +32,18 @@ import (
 //		}
 //
 // 3. gcp, awsなどのlogging
+//
+// Deprecated: ScrapingTaskManagerのLoggerを使ってください。どのメソッドからも呼ばれません。
 type OpenLog func() (*os.File, error)
 
 // CloseLogのCallBack関数です。
+//
+// Deprecated: ScrapingTaskManagerのLoggerを使ってください。どのメソッドからも呼ばれません。
 type CloseLog func(file *os.File) error
 
-// WaitVisible, WaitEnableのCallback関数です。
-// HeadlessならWaitEnableを選択してください。
-type WaitLogic func() error
+// 処理の後に何を待つかです。WaitNetworkIdle, WaitSelector, WaitURL, WaitFunc, WaitLoadなどで作る。
+// 条件を満たすか、タイムアウトするまで待つ。
+type WaitLogic func(ctx context.Context) error
 
 // Actionの順番に処理されるが、中で呼ぶブラウザの処理が同期的な処理とは限らないので注意。
 
@@ -50,20 +53,87 @@ type ScrapingTaskManager struct {
 	Height                int64
 	ScreenShotLogPath     string
 	ScreenShotLogPrefix   string
+	ScreenShotQuality     int // jpeg, webpのスクリーンショットの画質。1から100。0なら90。それ以外はValidateでエラーになる。
 	SiteTopUrl            string
 	LogInUrl              string
 	LogOutUrl             string
 	LoginPassword         string
-	LoginPasswordSel      string
+	LoginPasswordSel      Selector
 	LoginUsername         string
-	LoginUsernameSel      string
-	LoginButtonSel        string
+	LoginUsernameSel      Selector
+	LoginButtonSel        Selector
+	LoginSuccessSel       Selector      // ログインできたときにだけ表示される要素。空ならcookieだけで判断する。
+	LoginErrorSel         Selector      // ユーザー名かパスワードが間違っているときのエラーメッセージ
+	LoginCaptchaSel       Selector      // 画像認証などが求められたときに表示される要素
+	LoginTwoFactorSel     Selector      // 2段階認証のコードの入力欄
+	LoginOTPButtonSel     Selector      // 2段階認証のコードの送信ボタン。空ならEnterで送信する。
+	OTPProvider           OTPProvider   // 2段階認証のコードを返す。nilなら2段階認証はできない。
 	AgePermissionUrl      string        // 年齢認証が求められるurl
-	AgePermissionSel      string        // 年齢認証が求められたときにYesを押すボタンのタグ
-	AgePermissionNextSel  string        // 年齢認証が求められたときにYesを押した後に移動するページにあるSelector
+	AgePermissionSel      Selector      // 年齢認証が求められたときにYesを押すボタンのタグ
+	AgePermissionNextSel  Selector      // 年齢認証が求められたときにYesを押した後に移動するページにあるSelector
+	WorkUrl               string        // 作品ページのurl。%sに作品IDが入る。
+	WorkTitleSel          Selector      // 作品ページの作品名
+	WorkMakerSel          Selector      // 作品ページのサークル名、メーカー名
+	WorkPriceSel          Selector      // 作品ページの販売価格。セール中ならセール価格。
+	WorkRegularPriceSel   Selector      // 作品ページのセール中にだけ表示される定価
+	WorkPointSel          Selector      // 作品ページの付与されるポイント
+	WorkOutlineSel        Selector      // 作品ページの販売日やジャンルが書いてある表の行。thが項目名、tdが値。
+	WorkGenreSel          Selector      // 作品ページのジャンルのリンク
+	WorkSampleImageSel    Selector      // 作品ページのサンプル画像。data-srcかsrcを使う。
+	WorkDescriptionSel    Selector      // 作品ページの作品内容
+	SearchUrl             string        // 検索結果ページのurl。条件はこの後ろにつく。
+	SearchItemSel         Selector      // 検索結果の1件分の要素
+	SearchItemTitleSel    Selector      // 検索結果の1件の中の作品名のリンク
+	SearchItemPriceSel    Selector      // 検索結果の1件の中の価格
+	SearchItemRatingSel   Selector      // 検索結果の1件の中の評価の星
+	SearchNextSel         Selector      // 検索結果の次のページへのリンク
+	RankingUrl            string        // ランキングページのurl。集計期間とカテゴリはこの後ろにつく。
+	RankingItemSel        Selector      // ランキングの1件分の要素
+	RankingItemRankSel    Selector      // ランキングの1件の中の順位
+	RankingItemTitleSel   Selector      // ランキングの1件の中の作品名のリンク
+	RankingItemMakerSel   Selector      // ランキングの1件の中のサークル名、メーカー名
+	RankingItemPriceSel   Selector      // ランキングの1件の中の価格
+	RankingItemSalesSel   Selector      // ランキングの1件の中の販売数などの表示
+	PurchaseHistoryUrl    string        // 購入履歴ページのurl
+	PurchaseItemSel       Selector      // 購入履歴の1件分の要素
+	PurchaseItemTitleSel  Selector      // 購入履歴の1件の中の作品名のリンク
+	PurchaseItemMakerSel  Selector      // 購入履歴の1件の中のサークル名、メーカー名
+	PurchaseItemDateSel   Selector      // 購入履歴の1件の中の購入日
+	PurchaseItemPriceSel  Selector      // 購入履歴の1件の中の支払った価格
+	PurchaseDownloadSel   Selector      // 購入履歴の1件の中のダウンロードボタン。あればダウンロードできる。
+	PurchaseNextSel       Selector      // 購入履歴の次のページへのリンク
+	WishlistUrl           string        // お気に入りページのurl
+	WishlistItemSel       Selector      // お気に入りの1件分の要素
+	WishlistItemTitleSel  Selector      // お気に入りの1件の中の作品名のリンク
+	WishlistItemMakerSel  Selector      // お気に入りの1件の中のサークル名、メーカー名
+	WishlistItemPriceSel  Selector      // お気に入りの1件の中の販売価格。セール中ならセール価格。
+	WishlistRegularSel    Selector      // お気に入りの1件の中のセール中にだけ表示される定価
+	WishlistNextSel       Selector      // お気に入りの次のページへのリンク
 	DefaultTimeSpan       time.Duration // 実行時に待つ時間のデフォルト値
-	OpenLog               OpenLog
-	CloseLog              CloseLog
+	WaitLogic             WaitLogic     // 処理の後に待つ条件。nilならDefaultTimeSpanだけ待つ。
+	TaskTimeout           time.Duration // 処理1つごとのタイムアウト。0なら全体のcontextの期限まで待つ。処理の後に待つ時間には使わない。
+	RetryPolicy           *RetryPolicy  // 失敗した処理をやり直す設定。nilならやり直さない。
+	PriceStore            *PriceStore   // 設定すると作品の価格を記録する
+	CookieSecret          string        // 保存するcookieを暗号化する鍵
+	CookieMaxAge          time.Duration // 保存したcookieを使う期間。0なら個々のcookieの有効期限だけで判断する。
+	CookieFilePath        string        // EnsureLoggedInTasksで読み込み、保存するcookieのファイル
+	LoginRetries          int           // EnsureLoggedInTasksでログインをやり直す回数
+	Logger                *slog.Logger  // ログの出力先。nilならslog.Default()。
+	Trace                 *Trace        // 設定すると処理ごとの結果とスクリーンショットを記録する
+	OpenLog               OpenLog       // Deprecated: Loggerを使ってください。
+	CloseLog              CloseLog      // Deprecated: Loggerを使ってください。
+
+	// ユーザー名とパスワードを返す。nilならLoginUsername, LoginPasswordを使う。
+	Credentials CredentialProvider
+
+	// 設定すると、処理が失敗したときにページ全体のスクリーンショット、url、html、コンソールのログを保存する。
+	FailureCapture *FailureCapture
+
+	// VisualCheckTasksで画面をベースラインと比べる設定。nilならデフォルトの設定を使う。
+	VisualCheck *VisualCheck
+
+	// WithQueryType, WithFrameで指定したSelectorの解釈の仕方。
+	queryOptions []chromedp.QueryOption
 }
 
 // logが書けることの確認。
@@ -72,76 +142,19 @@ func IsEnableLog() (bool, error) {
 	return true, nil
 }
 
-// ログをOpenする。
-// gcpのcloud loggingを使う場合は
-// defer CloseLog()でちゃんとファイルが閉じることを保証すること。
-// この関数を渡せるように
-// func OpenLog() (*os.File, error) {
-
-// 	isLocal := false // ローカル環境かどうかの判定方法に合わせて適切な値を設定する
-
-// 	var file *os.File
-
-// 	if isLocal {
-// 		// ローカル環境の場合、ファイルにログを書き込む
-// 		fileName := "app.log"
-// 		file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("ログファイルを開く際にエラーが発生しました: %v", err)
-// 		}
-
-// 		log.SetOutput(file)
-// 	} else {
-// 		//
-// 		// GCP上の場合、Cloud Loggingにログを送信する
-// 		// credential使うのはあまり良くないので、iam+serviceaccountで設定する。
-// 		projectID := "your-project-id"
-// 		credentialsFile := "/path/to/credentials.json"
-
-// 		ctx := context.Background()
-// 		client, err := logging.NewClient(ctx, projectID, option.WithCredentialsFile(credentialsFile))
-// 		if err != nil {
-// 			return nil, fmt.Errorf("Cloud Loggingクライアントの作成に失敗しました: %v", err)
-// 		}
-
-// 		logger := client.Logger("my-log")
-// 		log.SetOutput(logger.Writer(logging.Info))
-// 	}
-
-// 	return file, nil
-
-// }
-
-// func CloseLog(file *os.File) error {
-
-// 	isLocal := false // ローカル環境かどうかの判定方法に合わせて適切な値を設定する
-
-// 	if isLocal {
-// 		err := file.Close()
-// 		if err != nil {
-// 			return fmt.Errorf("Cloud Loggingクライアントの作成に失敗しました: %v", err)
-// 		}
-// 	}
-
-// 	// gcpなどでは常にnil
-
-// 	return nil
-// }
+// ログの出力先はLoggerで差し替える。
+// ファイルにはNewJSONFileLogger、docker logsなどにはNewStdoutLoggerを使う。
+// gcp, awsなどのloggingに送る場合は、送るslog.Handlerを実装してslog.Newに渡す。
 
 // 年齢認証通過後か判定
 func (s ScrapingTaskManager) IsAgeVerificationTasks(targetCookieName string, targetCookieValue string, valid *bool) chromedp.Tasks {
 
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
-
-	// 判定が終わるまでunkownなのでnilを入れる
-	valid = nil
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
+		s.stepAction(step{Task: "IsAgeVerification", Failed: "cookieが取得できませんでした。"}, func(ctx context.Context) error {
+			// 判定が終わるまではfalseにしておく
+			*valid = false
 			cookies, err := network.GetCookies().Do(ctx)
 			if err != nil {
-				log.Println("cookieが取得できませんでした。", err)
 				return err
 			}
 
@@ -152,10 +165,6 @@ func (s ScrapingTaskManager) IsAgeVerificationTasks(targetCookieName string, tar
 				}
 			}
 
-			if valid == nil {
-				*valid = false
-			}
-
 			return nil
 		}),
 	}
@@ -171,19 +180,24 @@ func (s ScrapingTaskManager) ErrorTask(v string) chromedp.Tasks {
 	}
 }
 
+// ページのurlが設定されているか確認する。
+// name ScrapingTaskManagerのフィールド名。エラーのメッセージに使う。
+func requireUrl(name string, v string) error {
+	if v == "" {
+		return fmt.Errorf("%sが設定されていません。", name)
+	}
+	return nil
+}
+
 // セッションが有効かどうかの確認を行う。
 func (s ScrapingTaskManager) IsSessionVerificationTasks(valid *bool) chromedp.Tasks {
 
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
-	// 判定が終わるまでunkownなのでnilを入れる
-	valid = nil
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
+		s.stepAction(step{Task: "IsSessionVerification", Failed: "cookieが取得できませんでした。"}, func(ctx context.Context) error {
+			// 判定が終わるまではfalseにしておく
+			*valid = false
 			cookies, err := network.GetCookies().Do(ctx)
 			if err != nil {
-				log.Println("cookieが取得できませんでした。", err)
 				return err
 			}
 
@@ -194,10 +208,6 @@ func (s ScrapingTaskManager) IsSessionVerificationTasks(valid *bool) chromedp.Ta
 				}
 			}
 
-			if valid == nil {
-				*valid = false
-			}
-
 			return nil
 		}),
 	}
@@ -205,62 +215,33 @@ func (s ScrapingTaskManager) IsSessionVerificationTasks(valid *bool) chromedp.Ta
 
 // ウィンドウのサイズを調整する
 func (s ScrapingTaskManager) EmulateViewportTasks(width int64, height int64) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
+		s.stepAction(step{Task: "EmulateViewport", Failed: "ウィンドウサイズの変更ができませんでした。"}, func(ctx context.Context) error {
 			// ウィンドウサイズを指定（オプション）
-			err := chromedp.Run(ctx, chromedp.EmulateViewport(width, height))
-			if err != nil {
-				log.Println("ウィンドウサイズの変更ができませんでした。", err)
-				return err
-			}
-
-			return nil
+			return chromedp.Run(ctx, chromedp.EmulateViewport(width, height))
 		}),
 	}
 }
 
 // url全体を取得する
 func (s ScrapingTaskManager) LocationHrefTasks(href *string) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-
-			err := chromedp.Run(ctx,
+		s.stepAction(step{Task: "LocationHref", Failed: "hrefが取得できませんでした。"}, func(ctx context.Context) error {
+			return chromedp.Run(ctx,
 				chromedp.EvaluateAsDevTools("window.location.href", href),
 			)
-			if err != nil {
-				log.Println("hrefが取得できませんでした。", err)
-				return err
-			}
-
-			return nil
 		}),
 	}
 }
 
 // ウィンドウのサイズを取得する
 func (s ScrapingTaskManager) ViewSizeTasks(width *int64, height *int64) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			// ウィンドウサイズを指定（オプション）
-			err := chromedp.Run(ctx,
+		s.stepAction(step{Task: "ViewSize", Failed: "ウィンドウサイズが取得できませんでした。"}, func(ctx context.Context) error {
+			return chromedp.Run(ctx,
 				chromedp.EvaluateAsDevTools("window.innerHeight", height),
 				chromedp.EvaluateAsDevTools("window.innerWidth", width),
 			)
-			if err != nil {
-				log.Println("ウィンドウサイズが取得できませんでした。。", err)
-				return err
-			}
-
-			return nil
 		}),
 	}
 }
@@ -268,9 +249,9 @@ func (s ScrapingTaskManager) ViewSizeTasks(width *int64, height *int64) chromedp
 // logのためにscreenShotをとる。
 // args:
 //
-//			sel h1, div1
+//			sel h1, div1。nilか空文字ならページ全体。
 //		 fileNameFormat prefixの後のファイル名。自由形式。空の文字列でも良い。
-//	  fileExtension 現時点ではpng固定。
+//	  fileExtension png, jpg, jpeg, webp, pdfのどれか。jpeg, webpの画質はScreenShotQuality。
 func (s ScrapingTaskManager) TakeScreenShotLogTasks(sel interface{}, logFileName string, fileExtension string) chromedp.Tasks {
 	// スクリーンショットの名称指定。
 	currentTime := time.Now().Format(s.ScreenShotLogPrefix)
@@ -279,44 +260,48 @@ func (s ScrapingTaskManager) TakeScreenShotLogTasks(sel interface{}, logFileName
 	return s.TakeScreenShotTasks(sel, fileName)
 }
 
-// ウィンドウのサイズを調整する
 // screenShotをとる。
+// 形式はfileNameの拡張子で決まる。png, jpg, jpeg, webp, pdfに対応。pdfは常にページ全体になる。
+// pdfはヘッドレスモードでしか保存できない。ヘッドレスモードでなければErrPDFRequiresHeadless。
 // args:
 //
-//		sel h1, div1
+//		sel h1, div1。nilか空文字ならページ全体。要素は見えるまでスクロールしてから撮る。
 //	 fileName パスと拡張子まで含めた
 func (s ScrapingTaskManager) TakeScreenShotTasks(sel interface{}, fileName string) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
+	format, err := screenshotFormat(fileName)
+	if err != nil {
+		return s.ErrorTask(err.Error())
+	}
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			var imageBuf []byte
+		s.stepAction(step{Task: "TakeScreenShot", Sel: sel, Args: []string{fileName}, Failed: "スクリーンショットが取得できませんでした。"}, func(ctx context.Context) error {
 			// スクリーンショットを取得
-			// スクリーンショットの名称指定。
-			err := chromedp.Screenshot(sel, &imageBuf, chromedp.NodeVisible, chromedp.ByQuery).Do(ctx)
+			imageBuf, err := s.screenshot(ctx, sel, format)
 			if err != nil {
-				log.Println("スクリーンショットが取得できませんでした。", err)
 				return err
 			}
 
 			// スクリーンショットをファイルに保存
 			err = os.WriteFile(fileName, imageBuf, 0640)
 			if err != nil {
-				log.Println("スクリーンショットをファイルに保存できませんでした。", err)
-				return err
+				return fmt.Errorf("スクリーンショットをファイルに保存できませんでした。: %w", err)
 			}
 
+			if s.Trace != nil {
+				return s.Trace.addScreenshot(ctx, strings.TrimPrefix(filepath.Ext(fileName), "."), imageBuf)
+			}
 			return nil
 		}),
 	}
 }
 
+// ページ全体のscreenShotをとる。表示されていない部分も含む。
+// fileName パスと拡張子まで含めた。形式はTakeScreenShotTasksと同じ。
+func (s ScrapingTaskManager) TakeFullScreenShotTasks(fileName string) chromedp.Tasks {
+	return s.TakeScreenShotTasks(nil, fileName)
+}
+
 // キー入力を行う。
 func (s ScrapingTaskManager) SendKeysTasks(sel interface{}, v string, t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
@@ -325,11 +310,11 @@ func (s ScrapingTaskManager) SendKeysTasks(sel interface{}, v string, t ...time.
 	}
 	return chromedp.Tasks{
 		chromedp.ActionFunc(func(ctx context.Context) error {
-			err := chromedp.SendKeys(sel, v).Do(ctx)
-			if err != nil {
-				log.Println("キー入力ができませんでした。", err)
-				// logなど。
-			}
+			// キー入力ができなくても止めない。エラーはログに出るが、ページの状態は保存しない。
+			s.stepAction(step{Task: "SendKeys", Sel: sel, Failed: "キー入力ができませんでした。", NoCapture: true}, func(ctx context.Context) error {
+				q, opts := s.query(sel)
+				return chromedp.SendKeys(q, v, opts...).Do(ctx)
+			}).Do(ctx)
 
 			return nil
 		}),
@@ -353,12 +338,16 @@ func (s ScrapingTaskManager) MovePageTasks(url string, t ...time.Duration) chrom
 	}
 
 	return chromedp.Tasks{
-		chromedp.Navigate(url),
+		s.RetryTasks(nil, s.stepAction(step{Task: "MovePage", Url: url, Failed: "ページに移動できませんでした。"}, func(ctx context.Context) error {
+			return chromedp.Navigate(url).Do(ctx)
+		})),
 		s.WaitTasks(waitTime),
 	}
 }
 
 // サイトにログインする
+// ログインできたかをセッションのcookieとLoginSuccessSelで確認し、
+// できなかった場合はErrInvalidCredentialsなどの理由がわかるエラーを返す。
 // t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
 func (s ScrapingTaskManager) LoginSiteTasks(t ...time.Duration) chromedp.Tasks {
 
@@ -370,26 +359,39 @@ func (s ScrapingTaskManager) LoginSiteTasks(t ...time.Duration) chromedp.Tasks {
 	}
 
 	return chromedp.Tasks{
+		s.MovePageTasks(s.LogInUrl),
 		// ログイン状態かどうかの検証。
-		// chromedp.ActionFunc(func(ctx context.Context) error {
-		// 	var valid bool
-		// 	err := s.IsSessionVerificationTasks(&valid).Do(ctx)
-		// 	if err != nil {
-		// 		log.Println("ログイン済みかどうか確認できませんでした。", err)
-		// 		return err
-		// 	}
+		// cookieはページのurlで絞られるので、ログインページに移動してから確認する。
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var valid bool
+			err := s.IsSessionVerificationTasks(&valid).Do(ctx)
+			if err != nil {
+				s.logger().Error("ログイン済みかどうか確認できませんでした。", "error", err)
+				return err
+			}
 
-		// 	if valid {
-		// 		return errors.New("ログイン状態で、ログインを呼び出そうとしました。")
-		// 	}
-		// 	return nil
-		// }),
-		s.MovePageTasks(s.LogInUrl),
+			if valid {
+				s.logger().Info("ログイン状態で、ログインを呼び出そうとしました。")
+				return ErrAlreadyLoggedIn
+			}
+			return nil
+		}),
 		s.TakeScreenShotLogTasks("html", "login", "png"),
-		s.SendKeysTasks(s.LoginUsernameSel, s.LoginUsername, waitTime),
-		s.SendKeysTasks(s.LoginPasswordSel, s.LoginPassword, waitTime),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			c, err := s.credential(ctx)
+			if err != nil {
+				s.logger().Error("ユーザー名とパスワードを取得できませんでした。", "error", err)
+				return err
+			}
+			return chromedp.Tasks{
+				s.SendKeysTasks(s.LoginUsernameSel, c.Username, waitTime),
+				s.SendKeysTasks(s.LoginPasswordSel, c.Password, waitTime),
+			}.Do(ctx)
+		}),
 		s.ClickTasks(s.LoginButtonSel, waitTime),
 		s.WaitTasks(waitTime),
+		s.TwoFactorTasks(waitTime),
+		s.VerifyLoginTasks(),
 	}
 }
 
@@ -408,7 +410,7 @@ func (s ScrapingTaskManager) LogoutTasks(t ...time.Duration) chromedp.Tasks {
 			var valid bool
 			err := s.IsSessionVerificationTasks(&valid).Do(ctx)
 			if err != nil {
-				log.Println("ログイン済みかどうか確認できませんでした。", err)
+				s.logger().Error("ログイン済みかどうか確認できませんでした。", "error", err)
 				return err
 			}
 
@@ -427,9 +429,6 @@ func (s ScrapingTaskManager) LogoutTasks(t ...time.Duration) chromedp.Tasks {
 // 要素をクリックする
 // t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
 func (s ScrapingTaskManager) ClickTasks(sel interface{}, t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
@@ -437,39 +436,35 @@ func (s ScrapingTaskManager) ClickTasks(sel interface{}, t ...time.Duration) chr
 		waitTime = t[0]
 	}
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			err := chromedp.Click(sel).Do(ctx)
-			if err != nil {
-				log.Println("クリックできませんでした。", err)
-				return err
-			}
-			return nil
-		}),
+		s.RetryTasks(nil, s.stepAction(step{Task: "Click", Sel: sel, Failed: "クリックできませんでした。"}, func(ctx context.Context) error {
+			q, opts := s.query(sel)
+			return chromedp.Click(q, opts...).Do(ctx)
+		})),
 		s.WaitTasks(waitTime),
 	}
 }
 
 // 処理を待つのに使う
-// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+// WaitLogicがあれば、その条件を満たすまで待つ。無ければ決まった時間待つ。
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。WaitLogicがあれば使われない。
 func (s ScrapingTaskManager) WaitTasks(t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
 	} else {
 		waitTime = t[0]
 	}
+	// 待つ時間はWaitLogicのタイムアウトか、waitTimeで決まるので、TaskTimeoutでは区切らない。
+	if s.WaitLogic != nil {
+		return chromedp.Tasks{
+			s.stepAction(step{Task: "Wait", Failed: "待てませんでした。", NoTimeout: true}, func(ctx context.Context) error {
+				return s.WaitLogic(ctx)
+			}),
+		}
+	}
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			err := chromedp.Sleep(waitTime).Do(ctx)
-			if err != nil {
-				log.Println("待てませんでした。", err)
-				return err
-			}
-			log.Printf("%v待ちました。", waitTime)
-			return nil
+		s.stepAction(step{Task: "Wait", Args: []string{waitTime.String()}, Failed: "待てませんでした。", NoTimeout: true}, func(ctx context.Context) error {
+			return chromedp.Sleep(waitTime).Do(ctx)
 		}),
 	}
 }
@@ -479,9 +474,6 @@ func (s ScrapingTaskManager) WaitTasks(t ...time.Duration) chromedp.Tasks {
 // 主に正しく実行されているかなどの検査に使う。
 // t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
 func (s ScrapingTaskManager) TextContentTasks(sel interface{}, v *string, t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
@@ -489,19 +481,20 @@ func (s ScrapingTaskManager) TextContentTasks(sel interface{}, v *string, t ...t
 		waitTime = t[0]
 	}
 	return chromedp.Tasks{
+		s.RetryTasks(nil, chromedp.Tasks{
+			// s.WaitVisibleTasks(sel),
+			s.WaitEnableTasks(sel),
 
-		// s.WaitVisibleTasks(sel),
-		s.WaitEnableTasks(sel),
-
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			err := chromedp.TextContent(sel, v).Do(ctx)
+			s.stepAction(step{Task: "TextContent", Sel: sel, Failed: "textContentを取得できませんでした。"}, func(ctx context.Context) error {
+				q, opts := s.query(sel)
+				err := chromedp.TextContent(q, v, opts...).Do(ctx)
 
-			if err != nil {
-				log.Println("textContentを取得できませんでした。", err)
-				return err
-			}
-			log.Printf("textContentの値は%sです", *v)
-			return nil
+				if err != nil {
+					return err
+				}
+				s.logger().Debug("textContentを取得しました。", "value", *v)
+				return nil
+			}),
 		}),
 		// chromedp.TextContent(sel, v),
 		s.WaitTasks(waitTime),
@@ -517,12 +510,32 @@ func (s ScrapingTaskManager) TextContentTasks(sel interface{}, v *string, t ...t
 	}
 }
 
+// Selectorに合致する要素の数を数える。
+// 要素があるかどうかの判定に使う。待たないので、ページの読み込みが終わってから呼ぶこと。
+// sel 文字列かSelector。前に何もつけていない文字列は、ClickTasksなどと同じくdevtoolsの検索と同じように数える。
+// WithQueryType, WithFrameを指定した場合は、chromedpで要素を探して数える。
+func (s ScrapingTaskManager) CountTasks(sel interface{}, count *int) chromedp.Tasks {
+	return chromedp.Tasks{
+		s.stepAction(step{Task: "Count", Sel: sel, Failed: "要素の数を数えられませんでした。"}, func(ctx context.Context) error {
+			if len(s.queryOptions) > 0 {
+				q, qopts := s.query(sel)
+				var nodes []*cdp.Node
+				// 見つからなくても待たずに0を返す。
+				if err := chromedp.Nodes(q, &nodes, append(qopts, chromedp.AtLeast(0))...).Do(ctx); err != nil {
+					return err
+				}
+				*count = len(nodes)
+				return nil
+			}
+			return chromedp.Evaluate(withSelectorScript(fmt.Sprintf("findAll(%q).length", searchSelector(Selector(fmt.Sprint(sel))))), count).Do(ctx)
+		}),
+	}
+}
+
 // 要素が見えるのを待つ。Headlessなら永遠に表示されないので、使わない。
+// 見えるまで待ち続けるので、TaskTimeoutかWithTimeoutで時間を区切ること。
 // t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
 func (s ScrapingTaskManager) WaitVisibleTasks(sel interface{}, t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
@@ -531,24 +544,18 @@ func (s ScrapingTaskManager) WaitVisibleTasks(sel interface{}, t ...time.Duratio
 	}
 	// 何を待っているかのログを数秒置きに出す。
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			err := chromedp.WaitVisible(sel).Do(ctx)
-			if err != nil {
-				log.Println("要素が見えるのを待てませんでした。", err)
-				return err
-			}
-			return nil
+		s.stepAction(step{Task: "WaitVisible", Sel: sel, Failed: "要素が見えるのを待てませんでした。"}, func(ctx context.Context) error {
+			q, opts := s.query(sel)
+			return chromedp.WaitVisible(q, opts...).Do(ctx)
 		}),
 		s.WaitTasks(waitTime),
 	}
 }
 
 // 要素が使えるようになるのを待つ。
+// 使えるようになるまで待ち続けるので、TaskTimeoutかWithTimeoutで時間を区切ること。
 // t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
 func (s ScrapingTaskManager) WaitEnableTasks(sel interface{}, t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
@@ -556,13 +563,9 @@ func (s ScrapingTaskManager) WaitEnableTasks(sel interface{}, t ...time.Duration
 		waitTime = t[0]
 	}
 	return chromedp.Tasks{
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			err := chromedp.WaitEnabled(sel).Do(ctx)
-			if err != nil {
-				log.Println("要素が使えるようになるのを待てませんでした。", err)
-				return err
-			}
-			return nil
+		s.stepAction(step{Task: "WaitEnable", Sel: sel, Failed: "要素が使えるようになるのを待てませんでした。"}, func(ctx context.Context) error {
+			q, opts := s.query(sel)
+			return chromedp.WaitEnabled(q, opts...).Do(ctx)
 		}),
 		s.WaitTasks(waitTime),
 	}
@@ -570,9 +573,6 @@ func (s ScrapingTaskManager) WaitEnableTasks(sel interface{}, t ...time.Duration
 
 // 年齢認証を突破する
 func (s ScrapingTaskManager) AgeVerificationTasks(t ...time.Duration) chromedp.Tasks {
-	file, _ := s.OpenLog()
-
-	defer s.CloseLog(file)
 	var waitTime time.Duration
 	if len(t) == 0 {
 		waitTime = s.DefaultTimeSpan
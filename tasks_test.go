@@ -41,9 +41,9 @@ func TestMoveTopPageTasks(t *testing.T) {
 		ScreenShotLogPrefix:   os.Getenv("SCREENSHOT_LOG_PREFIX"),
 		LogInUrl:              os.Getenv("LOGIN_URL"),
 		LoginUsername:         os.Getenv("LOGIN_USERNAME"),
-		LoginUsernameSel:      os.Getenv("LOGIN_USERNAME_SEL"),
-		LoginPasswordSel:      os.Getenv("LOGIN_PASSWORD_SEL"),
-		LoginButtonSel:        os.Getenv("LOGIN_BUTTON_SEL"),
+		LoginUsernameSel:      Selector(os.Getenv("LOGIN_USERNAME_SEL")),
+		LoginPasswordSel:      Selector(os.Getenv("LOGIN_PASSWORD_SEL")),
+		LoginButtonSel:        Selector(os.Getenv("LOGIN_BUTTON_SEL")),
 		LogOutUrl:             os.Getenv("LOGOUT_URL"),
 
 		OpenLog: func() (*os.File, error) {
@@ -121,9 +121,9 @@ func TestLoginLogoutTasks(t *testing.T) {
 		ScreenShotLogPrefix:   os.Getenv("SCREENSHOT_LOG_PREFIX"),
 		LogInUrl:              os.Getenv("LOGIN_URL"),
 		LoginUsername:         os.Getenv("LOGIN_USERNAME"),
-		LoginUsernameSel:      os.Getenv("LOGIN_USERNAME_SEL"),
-		LoginPasswordSel:      os.Getenv("LOGIN_PASSWORD_SEL"),
-		LoginButtonSel:        os.Getenv("LOGIN_BUTTON_SEL"),
+		LoginUsernameSel:      Selector(os.Getenv("LOGIN_USERNAME_SEL")),
+		LoginPasswordSel:      Selector(os.Getenv("LOGIN_PASSWORD_SEL")),
+		LoginButtonSel:        Selector(os.Getenv("LOGIN_BUTTON_SEL")),
 		LogOutUrl:             os.Getenv("LOGOUT_URL"),
 
 		OpenLog: func() (*os.File, error) {
@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 処理がタイムアウトしたときのエラーです。errors.Asで取り出せる。
+// errors.Is(err, context.DeadlineExceeded)も成り立つ。
+type TimeoutError struct {
+	Task     string        // 処理の名前
+	Selector string        // 対象のSelector。無ければ空。
+	Url      string        // タイムアウトしたときのページのurl。取れなければ移動先のurl。
+	Waited   time.Duration // 待った時間
+	Err      error         // 元のエラー
+}
+
+func (e *TimeoutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sが%v待ってもタイムアウトしました。", e.Task, e.Waited.Round(time.Millisecond))
+	if e.Selector != "" {
+		fmt.Fprintf(&b, " selector: %s", e.Selector)
+	}
+	if e.Url != "" {
+		fmt.Fprintf(&b, " url: %s", e.Url)
+	}
+	return b.String()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// タイムアウトしたエラーを、処理の名前、Selector、今のurl、待った時間がわかるTimeoutErrorにする。
+// 中の処理ですでにTimeoutErrorになっていれば、そのまま返す。
+func timeoutError(ctx context.Context, st step, waited time.Duration, err error) error {
+	var te *TimeoutError
+	if errors.As(err, &te) {
+		return err
+	}
+	te = &TimeoutError{Task: st.Task, Url: st.Url, Waited: waited, Err: err}
+	if st.Sel != nil {
+		te.Selector = fmt.Sprint(st.Sel)
+	}
+	// 処理のcontextは終わっているので、別に時間を区切って今のurlを取る。
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+	defer cancel()
+	var href string
+	if chromedp.Evaluate("window.location.href", &href).Do(ctx) == nil && href != "" {
+		te.Url = href
+	}
+	return te
+}
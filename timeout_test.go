@@ -0,0 +1,88 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TaskTimeoutで処理が区切られて、何の処理かわかるエラーになるか確認。
+func TestStepActionTimeout(t *testing.T) {
+	s := ScrapingTaskManager{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TaskTimeout: 50 * time.Millisecond,
+	}
+	action := s.stepAction(step{Task: "WaitVisible", Sel: "#button", Url: "https://example.com/"}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	err := action.Do(context.Background())
+
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("stepAction() error = %v, want TimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("stepAction() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if te.Task != "WaitVisible" || te.Selector != "#button" || te.Url != "https://example.com/" {
+		t.Errorf("stepAction() error = %#v", te)
+	}
+	if te.Waited < s.TaskTimeout {
+		t.Errorf("TimeoutError.Waited = %v, want >= %v", te.Waited, s.TaskTimeout)
+	}
+	for _, want := range []string{"WaitVisible", "#button", "https://example.com/"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("TimeoutError.Error() = %v, want contains %v", err.Error(), want)
+		}
+	}
+}
+
+// 中の処理のTimeoutErrorは、外側の処理でもそのまま返すことの確認。
+func TestStepActionTimeoutNested(t *testing.T) {
+	s := ScrapingTaskManager{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	short := s
+	short.TaskTimeout = 10 * time.Millisecond
+	inner := short.stepAction(step{Task: "Click", Sel: "#inner"}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	outer := s.stepAction(step{Task: "Login"}, func(ctx context.Context) error {
+		return inner.Do(ctx)
+	})
+
+	var te *TimeoutError
+	if err := outer.Do(context.Background()); !errors.As(err, &te) || te.Task != "Click" {
+		t.Errorf("stepAction() error = %v, want TimeoutError of Click", err)
+	}
+}
+
+// 処理の後に待つ時間は、TaskTimeoutより長くてもタイムアウトにしないことの確認。
+func TestWaitTasksTimeout(t *testing.T) {
+	s := ScrapingTaskManager{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TaskTimeout: 10 * time.Millisecond,
+	}
+	if err := s.WaitTasks(50 * time.Millisecond).Do(context.Background()); err != nil {
+		t.Errorf("WaitTasks() error = %v, want nil", err)
+	}
+
+	// WaitLogicは自分のタイムアウトで止まる。
+	s.WaitLogic = func(ctx context.Context) error {
+		return pollWait(ctx, 100*time.Millisecond, "test", func(ctx context.Context) (bool, error) {
+			return false, nil
+		})
+	}
+	start := time.Now()
+	err := s.WaitTasks().Do(context.Background())
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitTasks() error = %v, want %v", err, ErrWaitTimeout)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("WaitTasks() waited %v, want >= %v", waited, 100*time.Millisecond)
+	}
+}
@@ -0,0 +1,186 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// トレースの記録の1件です。実行した処理1つ分。
+type TraceStep struct {
+	Task       string
+	Selector   string   `json:",omitempty"`
+	Url        string   `json:",omitempty"`
+	Args       []string `json:",omitempty"` // Selector, url以外の引数。入力した文字列などの秘密になりうるものは入れない。
+	Start      time.Time
+	End        time.Time
+	Attempt    int
+	Result     string // "ok"か"error"
+	Error      string `json:",omitempty"`
+	Screenshot string `json:",omitempty"` // 撮ったスクリーンショット。トレースのディレクトリからの相対パス。
+}
+
+// かかった時間。
+func (t TraceStep) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// 1回の実行で行った処理の記録です。
+// ScrapingTaskManagerのTraceに設定すると、処理ごとの結果とスクリーンショットがDirに記録される。
+// 実行が終わったらCloseでmanifest.jsonとreport.htmlを書き出すこと。
+type Trace struct {
+	Dir      string
+	Started  time.Time
+	Finished time.Time
+	Steps    []*TraceStep
+
+	mu sync.Mutex
+}
+
+// dirの下に今回の実行のトレースのディレクトリを作る。
+// ディレクトリ名は名前順に並べると古い順になる。
+func NewTrace(dir string) (*Trace, error) {
+	started := time.Now()
+	path := filepath.Join(dir, "trace_"+started.UTC().Format("20060102T150405.000Z"))
+	if err := os.MkdirAll(filepath.Join(path, "screenshots"), 0750); err != nil {
+		return nil, fmt.Errorf("トレースの保存先を作れませんでした。: %w", err)
+	}
+	return &Trace{Dir: path, Started: started}, nil
+}
+
+// 処理の記録を始める。終わったらfinishを呼ぶ。
+func (t *Trace) begin(st step, attempt int) *TraceStep {
+	ts := &TraceStep{Task: st.Task, Url: st.Url, Args: st.Args, Start: time.Now(), Attempt: attempt}
+	if st.Sel != nil {
+		ts.Selector = fmt.Sprint(st.Sel)
+	}
+	t.mu.Lock()
+	t.Steps = append(t.Steps, ts)
+	t.mu.Unlock()
+	return ts
+}
+
+func (t *Trace) finish(ts *TraceStep, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ts.End = time.Now()
+	ts.Result = "ok"
+	if err != nil {
+		ts.Result = "error"
+		ts.Error = err.Error()
+	}
+}
+
+// スクリーンショットをトレースのディレクトリに保存して、実行中の処理に紐づける。
+// ext 画像の拡張子
+func (t *Trace) addScreenshot(ctx context.Context, ext string, image []byte) error {
+	t.mu.Lock()
+	n := len(t.Steps)
+	t.mu.Unlock()
+
+	// 実行中の処理があれば、レポートの番号と同じにする。
+	task := "screenshot"
+	ts := traceStepFrom(ctx)
+	if ts != nil {
+		task = ts.Task
+		n--
+	}
+	name := filepath.Join("screenshots", fmt.Sprintf("%04d_%s.%s", n, task, ext))
+	if err := os.WriteFile(filepath.Join(t.Dir, name), image, 0640); err != nil {
+		return fmt.Errorf("トレースにスクリーンショットを保存できませんでした。: %w", err)
+	}
+	if ts != nil {
+		t.mu.Lock()
+		ts.Screenshot = filepath.ToSlash(name)
+		t.mu.Unlock()
+	}
+	return nil
+}
+
+// 実行中の処理の記録をcontextに入れるキー。
+type traceStepKey struct{}
+
+// contextに入れた実行中の処理の記録。無ければnil。
+func traceStepFrom(ctx context.Context) *TraceStep {
+	ts, _ := ctx.Value(traceStepKey{}).(*TraceStep)
+	return ts
+}
+
+// manifest.jsonとreport.htmlを書き出す。
+// 途中で失敗した実行でも呼ぶこと。失敗するまでの記録が残る。
+func (t *Trace) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Finished = time.Now()
+
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(t.Dir, "manifest.json"), b, 0640); err != nil {
+		return fmt.Errorf("トレースを保存できませんでした。: %w", err)
+	}
+
+	file, err := os.Create(filepath.Join(t.Dir, "report.html"))
+	if err != nil {
+		return fmt.Errorf("トレースのレポートを作れませんでした。: %w", err)
+	}
+	defer file.Close()
+	if err := traceReport.Execute(file, t); err != nil {
+		return fmt.Errorf("トレースのレポートを作れませんでした。: %w", err)
+	}
+	return nil
+}
+
+// 処理を時間の順に並べて、スクリーンショットのサムネイルをつけたレポート。
+var traceReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"offset": func(t *Trace, at time.Time) string {
+		return at.Sub(t.Started).Round(time.Millisecond).String()
+	},
+	"duration": func(ts *TraceStep) string {
+		return ts.Duration().Round(time.Millisecond).String()
+	},
+	"isImage": func(name string) bool {
+		return !strings.HasSuffix(name, ".pdf")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>トレース {{.Started.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+tr.error { background: #fdd; }
+td.num { text-align: right; white-space: nowrap; }
+img { max-width: 240px; max-height: 180px; }
+</style>
+</head>
+<body>
+<h1>トレース</h1>
+<p>{{.Started.Format "2006-01-02 15:04:05"}} から {{.Finished.Format "2006-01-02 15:04:05"}} まで、{{len .Steps}}件の処理</p>
+<table>
+<tr><th>#</th><th>開始</th><th>時間</th><th>処理</th><th>対象</th><th>回</th><th>結果</th><th>スクリーンショット</th></tr>
+{{- range $i, $s := .Steps}}
+<tr class="{{$s.Result}}">
+<td class="num">{{$i}}</td>
+<td class="num">{{offset $ $s.Start}}</td>
+<td class="num">{{duration $s}}</td>
+<td>{{$s.Task}}</td>
+<td>{{with $s.Selector}}<code>{{.}}</code><br>{{end}}{{with $s.Url}}<a href="{{.}}">{{.}}</a><br>{{end}}{{range $s.Args}}{{.}}<br>{{end}}</td>
+<td class="num">{{$s.Attempt}}</td>
+<td>{{$s.Result}}{{with $s.Error}}<br>{{.}}{{end}}</td>
+<td>{{with $s.Screenshot}}<a href="{{.}}">{{if isImage .}}<img src="{{.}}" loading="lazy">{{else}}{{.}}{{end}}</a>{{end}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 処理とスクリーンショットがmanifest.jsonとreport.htmlに記録されるか確認。
+func TestTrace(t *testing.T) {
+	trace, err := NewTrace(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ScrapingTaskManager{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Trace:  trace,
+	}
+
+	ctx := context.Background()
+	s.stepAction(step{Task: "MovePage", Url: "https://www.dlsite.com/"}, func(ctx context.Context) error {
+		return nil
+	}).Do(ctx)
+	s.stepAction(step{Task: "TakeScreenShot", Sel: "html"}, func(ctx context.Context) error {
+		return trace.addScreenshot(ctx, "png", []byte("png"))
+	}).Do(ctx)
+	s.stepAction(step{Task: "Click", Sel: "#button"}, func(ctx context.Context) error {
+		return errors.New("クリックできません")
+	}).Do(withAttempt(ctx, 2))
+
+	if err := trace.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(trace.Dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Trace
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []TraceStep{
+		{Task: "MovePage", Url: "https://www.dlsite.com/", Attempt: 1, Result: "ok"},
+		{Task: "TakeScreenShot", Selector: "html", Attempt: 1, Result: "ok", Screenshot: "screenshots/0001_TakeScreenShot.png"},
+		{Task: "Click", Selector: "#button", Attempt: 2, Result: "error", Error: "クリックできません"},
+	}
+	if len(got.Steps) != len(want) {
+		t.Fatalf("Trace.Steps = %d件, want %d件", len(got.Steps), len(want))
+	}
+	for i, w := range want {
+		g := *got.Steps[i]
+		if g.End.Before(g.Start) {
+			t.Errorf("Trace.Steps[%d] End = %v, Start = %v", i, g.End, g.Start)
+		}
+		g.Start, g.End = w.Start, w.End
+		if g.Task != w.Task || g.Selector != w.Selector || g.Url != w.Url || g.Attempt != w.Attempt ||
+			g.Result != w.Result || g.Error != w.Error || g.Screenshot != w.Screenshot {
+			t.Errorf("Trace.Steps[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(trace.Dir, want[1].Screenshot)); err != nil {
+		t.Errorf("スクリーンショットが保存されていません。: %v", err)
+	}
+
+	report, err := os.ReadFile(filepath.Join(trace.Dir, "report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"MovePage", "#button", "クリックできません", want[1].Screenshot} {
+		if !strings.Contains(string(report), s) {
+			t.Errorf("report.htmlに%sがありません。", s)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 画面がベースラインから変わっているときのエラーです。errors.Isで判定すること。
+// サイトのデザインが変わって、Selectorが合わなくなっているかもしれない。
+var ErrVisualMismatch = errors.New("画面がベースラインから変わっています。")
+
+// 画面の見た目をベースラインと比べる設定です。
+// ScrapingTaskManagerのVisualCheckに設定する。nilならデフォルトの設定を使う。
+type VisualCheck struct {
+	Dir       string     // ベースラインと差分の画像の保存先。空ならScreenShotLogPathの下のvisual。
+	Threshold float64    // 変わったピクセルの割合の上限。0なら0.01(1%)。
+	Tolerance float64    // 同じ色とみなす色の差。0から1。人の目で見た差に近くなるように計算する。0なら0.1。
+	Mask      []Selector // 比べる前に隠す要素のSelector。価格やバナーなど毎回変わるもの。
+	Update    bool       // trueなら比べずに、今の画面でベースラインを置き換える。
+}
+
+// 比べた結果です。
+type VisualResult struct {
+	Checkpoint string
+	Changed    int     // 変わったピクセルの数
+	Total      int     // ピクセルの数
+	Ratio      float64 // 変わったピクセルの割合
+	Baseline   string  // ベースラインの画像
+	Actual     string  // 今回の画像。変わっていたときだけ保存する。
+	Diff       string  // 変わったピクセルを赤くした画像。変わっていたときだけ保存する。
+}
+
+func (s ScrapingTaskManager) visualCheck() VisualCheck {
+	var v VisualCheck
+	if s.VisualCheck != nil {
+		v = *s.VisualCheck
+	}
+	if v.Dir == "" {
+		v.Dir = filepath.Join(s.ScreenShotLogPath, "visual")
+	}
+	if v.Threshold == 0 {
+		v.Threshold = 0.01
+	}
+	if v.Tolerance == 0 {
+		v.Tolerance = 0.1
+	}
+	return v
+}
+
+// チェックポイントの名前がDirの中のファイル名になるか確かめる。
+// "../x"のように、Dirの外に書き込む名前はエラー。
+func validateCheckpoint(checkpoint string) error {
+	if checkpoint == "" || checkpoint == "." || checkpoint == ".." || strings.ContainsAny(checkpoint, `/\`) {
+		return fmt.Errorf("チェックポイントの名前に使えない文字があります。: %q", checkpoint)
+	}
+	return nil
+}
+
+// 今の画面をcheckpointという名前のベースラインと比べる。
+// ベースラインが無ければ今の画面をベースラインとして保存する。
+// 変わったピクセルの割合がThresholdを超えたら、今回の画像と差分の画像を保存してErrVisualMismatchを返す。
+// checkpoint ファイル名になるので、/や\は使えない。
+// sel 比べる要素。nilか空文字ならページ全体。
+// out 結果を入れる。要らなければnilで良い。
+func (s ScrapingTaskManager) VisualCheckTasks(checkpoint string, sel interface{}, out *VisualResult) chromedp.Tasks {
+	v := s.visualCheck()
+	return chromedp.Tasks{
+		s.stepAction(step{Task: "VisualCheck", Sel: sel, Args: []string{checkpoint}, Failed: "画面をベースラインと比べられませんでした。"}, func(ctx context.Context) error {
+			if err := validateCheckpoint(checkpoint); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(v.Dir, 0750); err != nil {
+				return fmt.Errorf("ベースラインの保存先を作れませんでした。: %w", err)
+			}
+
+			b, err := s.maskedScreenshot(ctx, sel, v.Mask)
+			if err != nil {
+				return err
+			}
+			if s.Trace != nil {
+				s.Trace.addScreenshot(ctx, "png", b)
+			}
+
+			result := VisualResult{Checkpoint: checkpoint, Baseline: filepath.Join(v.Dir, checkpoint+".png")}
+			if out != nil {
+				defer func() { *out = result }()
+			}
+
+			baseline, err := os.ReadFile(result.Baseline)
+			if v.Update || errors.Is(err, os.ErrNotExist) {
+				if err := os.WriteFile(result.Baseline, b, 0640); err != nil {
+					return fmt.Errorf("ベースラインを保存できませんでした。: %w", err)
+				}
+				s.logger().Info("ベースラインを保存しました。", "checkpoint", checkpoint, "path", result.Baseline)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("ベースラインを読み込めませんでした。: %w", err)
+			}
+
+			want, err := png.Decode(bytes.NewReader(baseline))
+			if err != nil {
+				return fmt.Errorf("ベースラインを解釈できませんでした。: %w", err)
+			}
+			got, err := png.Decode(bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+
+			diff, changed, total := diffImages(want, got, v.Tolerance)
+			result.Changed, result.Total = changed, total
+			if total > 0 {
+				result.Ratio = float64(changed) / float64(total)
+			}
+			if result.Ratio <= v.Threshold {
+				s.logger().Info("画面はベースラインと同じです。", "checkpoint", checkpoint, "ratio", result.Ratio)
+				return nil
+			}
+
+			result.Actual = filepath.Join(v.Dir, checkpoint+"_actual.png")
+			result.Diff = filepath.Join(v.Dir, checkpoint+"_diff.png")
+			if err := os.WriteFile(result.Actual, b, 0640); err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, diff); err != nil {
+				return err
+			}
+			if err := os.WriteFile(result.Diff, buf.Bytes(), 0640); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s %.2f%%のピクセルが変わりました。差分は%s", ErrVisualMismatch, checkpoint, result.Ratio*100, result.Diff)
+		}),
+	}
+}
+
+// maskの要素を隠してpngのスクリーンショットを撮る。撮った後は元に戻す。
+func (s ScrapingTaskManager) maskedScreenshot(ctx context.Context, sel interface{}, mask []Selector) ([]byte, error) {
+	if len(mask) > 0 {
+		// ClickTasksなどと同じく、前に何もつけていないセレクタはdevtoolsの検索と同じように探す。
+		sels := make([]Selector, len(mask))
+		for i, sel := range mask {
+			sels[i] = searchSelector(sel)
+		}
+		b, err := json.Marshal(sels)
+		if err != nil {
+			return nil, err
+		}
+		// 複数のSelectorに合致する要素は、最初に隠す前のvisibilityだけを残す。
+		err = chromedp.Evaluate(withSelectorScript(fmt.Sprintf(`%s.forEach(sel => findAll(sel).forEach(e => {
+			if (!("visualCheckVisibility" in e.dataset)) {
+				e.dataset.visualCheckVisibility = e.style.visibility;
+			}
+			e.style.visibility = "hidden";
+		}))`, b)), nil).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer chromedp.Evaluate(`document.querySelectorAll("[data-visual-check-visibility]").forEach(e => {
+			e.style.visibility = e.dataset.visualCheckVisibility;
+			delete e.dataset.visualCheckVisibility;
+		})`, nil).Do(ctx)
+	}
+	return s.screenshot(ctx, sel, "png")
+}
+
+// 2つの画像をピクセルごとに比べて、変わったピクセルを赤くした画像と、変わったピクセルの数を返す。
+// 大きさが違う場合は、はみ出した部分も変わったものとして数える。
+// tolerance 同じ色とみなす色の差。0から1。
+func diffImages(want image.Image, got image.Image, tolerance float64) (*image.RGBA, int, int) {
+	wb, gb := want.Bounds(), got.Bounds()
+	width, height := max(wb.Dx(), gb.Dx()), max(wb.Dy(), gb.Dy())
+	diff := image.NewRGBA(image.Rect(0, 0, width, height))
+	// 色の差の最大値は35215。
+	maxDelta := 35215 * tolerance * tolerance
+
+	changed := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			wp, gp := image.Pt(wb.Min.X+x, wb.Min.Y+y), image.Pt(gb.Min.X+x, gb.Min.Y+y)
+			if !wp.In(wb) || !gp.In(gb) {
+				changed++
+				diff.Set(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+			wc, gc := want.At(wp.X, wp.Y), got.At(gp.X, gp.Y)
+			if colorDelta(wc, gc) > maxDelta {
+				changed++
+				diff.Set(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+			// 変わっていないところは薄い灰色にして、赤が目立つようにする。
+			l := uint8(255 - (255-luminance(gc))/4)
+			diff.Set(x, y, color.RGBA{R: l, G: l, B: l, A: 255})
+		}
+	}
+	return diff, changed, width * height
+}
+
+// 人の目で見た色の差。YIQ色空間での距離を使う。
+func colorDelta(a color.Color, b color.Color) float64 {
+	ay, ai, aq := yiq(a)
+	by, bi, bq := yiq(b)
+	dy, di, dq := ay-by, ai-bi, aq-bq
+	return 0.5053*dy*dy + 0.299*di*di + 0.1957*dq*dq
+}
+
+// 白い背景に重ねた色をYIQにする。
+func yiq(c color.Color) (float64, float64, float64) {
+	r, g, b, a := c.RGBA()
+	// RGBAはアルファを掛けた16bitの値なので、白と合成して8bitにする。
+	blend := func(v uint32) float64 {
+		return float64(v+(0xffff-a)) / 257
+	}
+	rf, gf, bf := blend(r), blend(g), blend(b)
+	y := rf*0.29889531 + gf*0.58662247 + bf*0.11448223
+	i := rf*0.59597799 - gf*0.27417610 - bf*0.32180189
+	q := rf*0.21147017 - gf*0.52261711 + bf*0.31114694
+	return y, i, q
+}
+
+func luminance(c color.Color) uint8 {
+	y, _, _ := yiq(c)
+	return uint8(y)
+}
@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// 変わったピクセルだけが数えられるか確認。
+func TestDiffImages(t *testing.T) {
+	fill := func(w int, h int, c color.Color) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	changed := fill(10, 10, white)
+	for x := 0; x < 10; x++ {
+		changed.Set(x, 0, color.RGBA{A: 255})
+	}
+	slight := fill(10, 10, color.RGBA{R: 250, G: 250, B: 250, A: 255})
+
+	tests := []struct {
+		name        string
+		want        image.Image
+		got         image.Image
+		wantChanged int
+		wantTotal   int
+		wantRed     *image.Point // 差分の画像で赤くなっているはずのピクセル
+	}{
+		{name: "same", want: fill(10, 10, white), got: fill(10, 10, white), wantChanged: 0, wantTotal: 100},
+		{name: "row", want: fill(10, 10, white), got: changed, wantChanged: 10, wantTotal: 100, wantRed: &image.Point{X: 0, Y: 0}},
+		{name: "tolerance", want: fill(10, 10, white), got: slight, wantChanged: 0, wantTotal: 100},
+		{name: "size", want: fill(10, 10, white), got: fill(10, 12, white), wantChanged: 20, wantTotal: 120, wantRed: &image.Point{X: 0, Y: 11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, gotChanged, gotTotal := diffImages(tt.want, tt.got, 0.1)
+			if gotChanged != tt.wantChanged || gotTotal != tt.wantTotal {
+				t.Errorf("diffImages() = %v/%v, want %v/%v", gotChanged, gotTotal, tt.wantChanged, tt.wantTotal)
+			}
+			if tt.wantRed != nil && diff.RGBAAt(tt.wantRed.X, tt.wantRed.Y) != (color.RGBA{R: 255, A: 255}) {
+				t.Errorf("diffImages() %vが赤くなっていません。", *tt.wantRed)
+			}
+		})
+	}
+}
+
+// ベースラインの保存先の外に書き込むチェックポイントの名前がエラーになるか確認。
+func TestValidateCheckpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		wantErr bool
+	}{
+		{name: "plain", args: "maniax-top"},
+		{name: "dots", args: "top.v2"},
+		{name: "empty", args: "", wantErr: true},
+		{name: "parent", args: "..", wantErr: true},
+		{name: "escape", args: "../x", wantErr: true},
+		{name: "subdir", args: "a/b", wantErr: true},
+		{name: "backslash", args: `..\x`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCheckpoint(tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("validateCheckpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
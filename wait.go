@@ -0,0 +1,208 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// 待つ条件を満たす前にタイムアウトしたときのエラーです。errors.Isで判定すること。
+var ErrWaitTimeout = errors.New("待つ条件を満たす前にタイムアウトしました。")
+
+// 条件を確認する間隔。
+const waitPollInterval = 100 * time.Millisecond
+
+// timeoutまでに条件を満たすまで、waitPollIntervalごとにcheckを呼ぶ。
+// ページの移動中はjsの実行が失敗することがあるので、checkのエラーでは止めずに、タイムアウトしたときに一緒に返す。
+// timeout 0ならctxが終わるまで待つ。
+// what ログに出す、何を待っているか。
+func pollWait(ctx context.Context, timeout time.Duration, what string, check func(ctx context.Context) (bool, error)) error {
+	return withWaitTimeout(ctx, timeout, what, func(ctx context.Context) error {
+		ticker := time.NewTicker(waitPollInterval)
+		defer ticker.Stop()
+		var lastErr error
+		for {
+			ok, err := check(ctx)
+			if err == nil && ok {
+				return nil
+			}
+			if err != nil {
+				lastErr = err
+			}
+			select {
+			case <-ctx.Done():
+				if lastErr != nil {
+					return fmt.Errorf("%w: %v", ctx.Err(), lastErr)
+				}
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// timeoutで区切ってfnを実行する。timeoutで終わったらErrWaitTimeoutを返す。
+func withWaitTimeout(ctx context.Context, timeout time.Duration, what string, fn func(ctx context.Context) error) error {
+	wctx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		wctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := fn(wctx)
+	// 呼び出し元のctxが終わった場合は、そのエラーをそのまま返す。
+	if err != nil && ctx.Err() == nil && errors.Is(wctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w %sを%v待ちました。: %v", ErrWaitTimeout, what, timeout, err)
+	}
+	return err
+}
+
+// 決まった時間待つ。WaitLogicが無いときと同じ。
+func WaitSleep(d time.Duration) WaitLogic {
+	return func(ctx context.Context) error {
+		return chromedp.Sleep(d).Do(ctx)
+	}
+}
+
+// 通信がidleの間止まるまで待つ。待ち始める前に始まった通信は数えない。
+// idle 0なら500ミリ秒。
+// timeout 0ならctxが終わるまで待つ。
+func WaitNetworkIdle(idle time.Duration, timeout time.Duration) WaitLogic {
+	if idle == 0 {
+		idle = 500 * time.Millisecond
+	}
+	return func(ctx context.Context) error {
+		lctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var mu sync.Mutex
+		inflight := map[network.RequestID]bool{}
+		last := time.Now()
+		chromedp.ListenTarget(lctx, func(ev interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch ev := ev.(type) {
+			case *network.EventRequestWillBeSent:
+				inflight[ev.RequestID] = true
+			case *network.EventLoadingFinished:
+				delete(inflight, ev.RequestID)
+			case *network.EventLoadingFailed:
+				delete(inflight, ev.RequestID)
+			default:
+				return
+			}
+			last = time.Now()
+		})
+
+		return pollWait(ctx, timeout, fmt.Sprintf("通信が%v止まるの", idle), func(ctx context.Context) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(inflight) == 0 && time.Since(last) >= idle, nil
+		})
+	}
+}
+
+// Selectorに合致する要素がページに現れるまで待つ。
+// timeout 0ならctxが終わるまで待つ。
+func WaitSelector(sel interface{}, timeout time.Duration) WaitLogic {
+	return func(ctx context.Context) error {
+		return withWaitTimeout(ctx, timeout, fmt.Sprintf("要素%vが現れるの", sel), func(ctx context.Context) error {
+			q, opts := selectorQuery(sel)
+			return chromedp.WaitReady(q, opts...).Do(ctx)
+		})
+	}
+}
+
+// urlが正規表現patternに合致するまで待つ。ページの移動を待つのに使う。
+// timeout 0ならctxが終わるまで待つ。
+func WaitURL(pattern string, timeout time.Duration) WaitLogic {
+	re, err := regexp.Compile(pattern)
+	return func(ctx context.Context) error {
+		if err != nil {
+			return fmt.Errorf("urlの正規表現を解釈できませんでした。: %w", err)
+		}
+		return pollWait(ctx, timeout, fmt.Sprintf("urlが%sになるの", pattern), func(ctx context.Context) (bool, error) {
+			var href string
+			if err := chromedp.Evaluate("window.location.href", &href).Do(ctx); err != nil {
+				return false, err
+			}
+			return re.MatchString(href), nil
+		})
+	}
+}
+
+// jsの式expressionがtrueとみなせる値になるまで待つ。
+// timeout 0ならctxが終わるまで待つ。
+func WaitFunc(expression string, timeout time.Duration) WaitLogic {
+	return func(ctx context.Context) error {
+		return pollWait(ctx, timeout, fmt.Sprintf("%sが満たされるの", expression), func(ctx context.Context) (bool, error) {
+			var ok bool
+			err := chromedp.Evaluate(fmt.Sprintf("!!(%s)", expression), &ok).Do(ctx)
+			return ok, err
+		})
+	}
+}
+
+// loadイベントが終わる(document.readyStateがcomplete)まで待つ。
+// すでに終わっていればすぐに戻るので、ページの移動を待つ場合はWaitURLと組み合わせること。
+// timeout 0ならctxが終わるまで待つ。
+func WaitLoad(timeout time.Duration) WaitLogic {
+	return WaitFunc(`document.readyState === "complete"`, timeout)
+}
+
+// DOMContentLoadedイベントが終わる(document.readyStateがloadingでない)まで待つ。
+// timeout 0ならctxが終わるまで待つ。
+func WaitDOMContentLoaded(timeout time.Duration) WaitLogic {
+	return WaitFunc(`document.readyState !== "loading"`, timeout)
+}
+
+// 全ての条件を順に待つ。
+func WaitAll(ws ...WaitLogic) WaitLogic {
+	return func(ctx context.Context) error {
+		for _, w := range ws {
+			if err := w(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// 文字列で指定した待つ条件をWaitLogicにする。コマンドの引数や設定に使う。
+// 空かsleepならnilを返す。決まった時間待つことになる。
+// network-idle, load, domcontentloaded, selector=Selector, url=正規表現, js=式 のどれか。
+// timeout 条件を満たすまで待つ時間の上限。
+func ParseWaitLogic(spec string, timeout time.Duration) (WaitLogic, error) {
+	switch spec {
+	case "", "sleep":
+		return nil, nil
+	case "network-idle":
+		return WaitNetworkIdle(0, timeout), nil
+	case "load":
+		return WaitLoad(timeout), nil
+	case "domcontentloaded":
+		return WaitDOMContentLoaded(timeout), nil
+	}
+	kind, v, ok := strings.Cut(spec, "=")
+	if ok && v != "" {
+		switch kind {
+		case "selector":
+			return WaitSelector(v, timeout), nil
+		case "url":
+			if _, err := regexp.Compile(v); err != nil {
+				return nil, fmt.Errorf("urlの正規表現を解釈できませんでした。: %w", err)
+			}
+			return WaitURL(v, timeout), nil
+		case "js":
+			return WaitFunc(v, timeout), nil
+		}
+	}
+	return nil, fmt.Errorf("待つ条件に対応していません。sleep, network-idle, load, domcontentloaded, selector=, url=, js=のどれかにしてください。: %s", spec)
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 条件を満たすまで繰り返し確認し、満たさなければErrWaitTimeoutになるか確認。
+func TestPollWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		ready    int // 何回目の確認で条件を満たすか。0なら満たさない。
+		checkErr error
+		wantErr  error
+	}{
+		{name: "ready", ready: 1},
+		{name: "ready later", ready: 3},
+		{name: "timeout", wantErr: ErrWaitTimeout},
+		{name: "timeout with error", checkErr: errors.New("Execution context was destroyed."), wantErr: ErrWaitTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			err := pollWait(context.Background(), 500*time.Millisecond, "test", func(ctx context.Context) (bool, error) {
+				n++
+				if tt.checkErr != nil {
+					return false, tt.checkErr
+				}
+				return tt.ready > 0 && n >= tt.ready, nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("pollWait() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.ready > 0 && n != tt.ready {
+				t.Errorf("pollWait() checked %v times, want %v", n, tt.ready)
+			}
+		})
+	}
+}
+
+// 呼び出し元のcontextが終わった場合は、タイムアウトにしないことの確認。
+func TestPollWaitCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := pollWait(ctx, time.Second, "test", func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("pollWait() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// 文字列の待つ条件を解釈できるか確認。
+func TestParseWaitLogic(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", spec: "", wantNil: true},
+		{name: "sleep", spec: "sleep", wantNil: true},
+		{name: "network-idle", spec: "network-idle"},
+		{name: "load", spec: "load"},
+		{name: "domcontentloaded", spec: "domcontentloaded"},
+		{name: "selector", spec: "selector=#main"},
+		{name: "url", spec: "url=/mypage/"},
+		{name: "js", spec: "js=window.loaded"},
+		{name: "invalid url", spec: "url=(", wantErr: true},
+		{name: "empty selector", spec: "selector=", wantErr: true},
+		{name: "unknown", spec: "idle", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWaitLogic(tt.spec, time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWaitLogic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("ParseWaitLogic() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
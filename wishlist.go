@@ -0,0 +1,104 @@
+package tasks
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// お気に入りに入れた作品です。
+type WishlistItem struct {
+	ProductID    string
+	Title        string
+	Maker        string
+	Price        int  // 今の販売価格(円)。セール中ならセール価格。
+	RegularPrice int  // 定価(円)
+	OnSale       bool // セール中か
+	Discount     int  // 割引率(%)
+}
+
+func (w WishlistItem) CSVHeader() []string {
+	return []string{"product_id", "title", "maker", "price", "regular_price", "on_sale", "discount"}
+}
+
+func (w WishlistItem) CSVRecord() []string {
+	return []string{
+		w.ProductID,
+		w.Title,
+		w.Maker,
+		strconv.Itoa(w.Price),
+		strconv.Itoa(w.RegularPrice),
+		strconv.FormatBool(w.OnSale),
+		strconv.Itoa(w.Discount),
+	}
+}
+
+// お気に入りのページを辿って、お気に入りに入れた作品の一覧を取得する。
+// ログインしてから呼ぶこと。最後のページまで辿る。
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) WishlistTasks(out *[]WishlistItem, t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+	if err := requireUrl("WishlistUrl", s.WishlistUrl); err != nil {
+		return s.ErrorTask(err.Error())
+	}
+
+	fields := map[string]Selector{
+		"Title":   s.WishlistItemTitleSel,
+		"Maker":   s.WishlistItemMakerSel,
+		"Price":   s.WishlistItemPriceSel,
+		"Regular": s.WishlistRegularSel,
+	}
+	return chromedp.Tasks{
+		s.MovePageTasks(s.WishlistUrl, waitTime),
+		s.paginateTasks(s.WishlistNextSel, 0, waitTime, func(ctx context.Context) error {
+			var items []listItem
+			if err := s.listTasks(s.WishlistItemSel, fields, &items).Do(ctx); err != nil {
+				return err
+			}
+			*out = append(*out, wishlistItems(items)...)
+			return nil
+		}),
+	}
+}
+
+// 一覧ページの各項目をWishlistItemにする。作品IDが無いものは飛ばす。
+func wishlistItems(items []listItem) []WishlistItem {
+	var ws []WishlistItem
+	for _, item := range items {
+		id := ProductIDFromURL(item["Title"].Href)
+		if id == "" {
+			continue
+		}
+		w := WishlistItem{
+			ProductID: id,
+			Title:     item["Title"].Text,
+			Maker:     item["Maker"].Text,
+			Price:     parsePrice(item["Price"].Text),
+		}
+		// セール中は定価に取り消し線がついて別に表示される。
+		w.RegularPrice = parsePrice(item["Regular"].Text)
+		if w.RegularPrice > w.Price && w.Price > 0 {
+			w.OnSale = true
+			w.Discount = discountRate(w.RegularPrice, w.Price)
+		} else {
+			w.RegularPrice = w.Price
+		}
+		ws = append(ws, w)
+	}
+	return ws
+}
+
+// 定価とセール価格から割引率(%)を計算する。
+func discountRate(regular int, sale int) int {
+	if regular <= 0 || sale <= 0 || sale >= regular {
+		return 0
+	}
+	return (regular - sale) * 100 / regular
+}
@@ -0,0 +1,39 @@
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 一覧ページの項目がWishlistItemになり、セール中かどうか判定されるか確認。
+func TestWishlistItems(t *testing.T) {
+	items := []listItem{
+		{
+			"Title":   {Text: "セール中の作品", Href: "https://www.dlsite.com/maniax/work/=/product_id/RJ01000000.html"},
+			"Price":   {Text: "660円"},
+			"Regular": {Text: "1,320円"},
+		},
+		{
+			"Title": {Text: "定価の作品", Href: "https://www.dlsite.com/maniax/work/=/product_id/RJ01000001.html"},
+			"Maker": {Text: "サークル名"},
+			"Price": {Text: "880円"},
+		},
+	}
+	want := []WishlistItem{
+		{ProductID: "RJ01000000", Title: "セール中の作品", Price: 660, RegularPrice: 1320, OnSale: true, Discount: 50},
+		{ProductID: "RJ01000001", Title: "定価の作品", Maker: "サークル名", Price: 880, RegularPrice: 880},
+	}
+	if got := wishlistItems(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("wishlistItems() = %+v, want %+v", got, want)
+	}
+}
+
+// WishlistUrlを設定していないと、ページに移動する前にエラーになることの確認。
+func TestWishlistTasksUrlNotSet(t *testing.T) {
+	err := ScrapingTaskManager{}.WishlistTasks(&[]WishlistItem{}).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "WishlistUrl") {
+		t.Errorf("WishlistTasks() error = %v, want contains %v", err, "WishlistUrl")
+	}
+}
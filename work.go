@@ -0,0 +1,224 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// 作品ページから取得した作品の情報です。
+type Work struct {
+	ProductID    string
+	Url          string
+	Title        string
+	Maker        string    // サークル名、メーカー名
+	Price        int       // 定価(円)
+	SalePrice    int       // セール価格(円)。セール中でなければ0。
+	Points       int       // 付与されるポイント
+	ReleaseDate  time.Time // 販売日
+	Genres       []string
+	AgeRating    string // 年齢指定
+	FileFormat   string // ファイル形式
+	FileSize     string // ファイル容量
+	SampleImages []string
+	Description  string
+}
+
+// 作品ページのブラウザ側で取得した、加工前の値です。
+type rawWork struct {
+	Title        string
+	Maker        string
+	Price        string
+	RegularPrice string
+	Points       string
+	Outline      map[string]string // 表の項目名と値
+	Genres       []string
+	SampleImages []string
+	Description  string
+}
+
+// 作品ページの値をまとめて取得するjavascript。withSelectorScriptで実行する。
+// 引数のセレクタはScrapingTaskManagerのWork*Sel。
+const workScript = `(function(sel) {
+	const text = (s) => {
+		const e = s ? find(s) : null;
+		return e ? e.textContent.trim() : "";
+	};
+	const all = (s) => s ? findAll(s) : [];
+	const outline = {};
+	for (const tr of all(sel.Outline)) {
+		const th = tr.querySelector("th");
+		const td = tr.querySelector("td");
+		if (th && td) {
+			outline[th.textContent.trim()] = td.textContent.replace(/\s+/g, " ").trim();
+		}
+	}
+	return {
+		Title: text(sel.Title),
+		Maker: text(sel.Maker),
+		Price: text(sel.Price),
+		RegularPrice: text(sel.RegularPrice),
+		Points: text(sel.Points),
+		Outline: outline,
+		Genres: all(sel.Genre).map((e) => e.textContent.trim()),
+		SampleImages: all(sel.SampleImage).map((e) => e.dataset.src || e.getAttribute("src") || "").filter((v) => v !== ""),
+		Description: text(sel.Description),
+	};
+})(%s)`
+
+var (
+	digitsPattern = regexp.MustCompile(`[0-9]+`)
+	pricePattern  = regexp.MustCompile(`[0-9][0-9,]*`)
+	datePattern   = regexp.MustCompile(`([0-9]{4})[年/-]([0-9]{1,2})[月/-]([0-9]{1,2})`)
+	jst           = time.FixedZone("JST", 9*60*60)
+)
+
+// 作品ページのurlを返す。
+// WorkUrlが空か、作品IDを入れる%sが1つだけ書かれていなければエラーを返す。
+func (s ScrapingTaskManager) workURL(productID string) (string, error) {
+	if err := requireUrl("WorkUrl", s.WorkUrl); err != nil {
+		return "", err
+	}
+	// 書式が合っていなければ、fmtが%!(...)を入れる。
+	if !strings.Contains(s.WorkUrl, "%s") || strings.Contains(fmt.Sprintf(s.WorkUrl, ""), "%!") {
+		return "", fmt.Errorf("WorkUrlには作品IDを入れる%%sを1つだけ書いてください。: %s", s.WorkUrl)
+	}
+	return fmt.Sprintf(s.WorkUrl, productID), nil
+}
+
+// 作品ページに移動して、作品の情報を取得する。
+// 年齢認証が表示された場合は、AgeVerificationTasksで突破してから取得する。
+// PriceStoreが設定されていれば、取得した価格を記録する。
+// productID RJ123456のような作品ID
+// t 待つのに使う。指定しない場合は、デフォルトの時間が使われる。配列の最初にあるものしか使われない。
+func (s ScrapingTaskManager) ScrapeWorkTasks(productID string, out *Work, t ...time.Duration) chromedp.Tasks {
+	var waitTime time.Duration
+	if len(t) == 0 {
+		waitTime = s.DefaultTimeSpan
+	} else {
+		waitTime = t[0]
+	}
+
+	url, err := s.workURL(productID)
+	if err != nil {
+		return s.ErrorTask(err.Error())
+	}
+	return chromedp.Tasks{
+		s.MovePageTasks(url, waitTime),
+		// 年齢認証が出ていれば突破する。
+		s.stepAction(step{Task: "AgeGate", Url: url, Failed: "年齢認証を突破できませんでした。", NoTimeout: true}, func(ctx context.Context) error {
+			var count int
+			if err := s.CountTasks(s.AgePermissionSel, &count).Do(ctx); err != nil {
+				return err
+			}
+			if count == 0 {
+				return nil
+			}
+			s.logger().Info("年齢認証が求められました。", "url", url)
+			gate := s
+			gate.AgePermissionUrl = url
+			return gate.AgeVerificationTasks(waitTime).Do(ctx)
+		}),
+		s.stepAction(step{Task: "ScrapeWork", Url: url, Failed: "作品の情報を取得できませんでした。"}, func(ctx context.Context) error {
+			// ClickTasksなどと同じく、前に何もつけていないセレクタはdevtoolsの検索と同じように探す。
+			sel, err := json.Marshal(map[string]Selector{
+				"Title":        searchSelector(s.WorkTitleSel),
+				"Maker":        searchSelector(s.WorkMakerSel),
+				"Price":        searchSelector(s.WorkPriceSel),
+				"RegularPrice": searchSelector(s.WorkRegularPriceSel),
+				"Points":       searchSelector(s.WorkPointSel),
+				"Outline":      searchSelector(s.WorkOutlineSel),
+				"Genre":        searchSelector(s.WorkGenreSel),
+				"SampleImage":  searchSelector(s.WorkSampleImageSel),
+				"Description":  searchSelector(s.WorkDescriptionSel),
+			})
+			if err != nil {
+				return err
+			}
+
+			var raw rawWork
+			err = chromedp.Evaluate(withSelectorScript(fmt.Sprintf(workScript, sel)), &raw).Do(ctx)
+			if err != nil {
+				return err
+			}
+			if raw.Title == "" {
+				return fmt.Errorf("作品名が見つかりませんでした。: %s", url)
+			}
+
+			*out = raw.work()
+			out.ProductID = productID
+			out.Url = url
+			s.logger().Info("作品の情報を取得しました。", "productID", out.ProductID, "title", out.Title)
+
+			if s.PriceStore != nil {
+				if err := s.PriceStore.RecordWork(*out, time.Now()); err != nil {
+					s.logger().Error("価格を記録できませんでした。", "error", err)
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+}
+
+// 加工前の値をWorkにする。
+func (raw rawWork) work() Work {
+	w := Work{
+		Title:        raw.Title,
+		Maker:        raw.Maker,
+		Points:       parsePrice(raw.Points),
+		Genres:       raw.Genres,
+		AgeRating:    raw.Outline["年齢指定"],
+		FileFormat:   raw.Outline["ファイル形式"],
+		FileSize:     raw.Outline["ファイル容量"],
+		SampleImages: raw.SampleImages,
+		Description:  raw.Description,
+	}
+	for i, src := range w.SampleImages {
+		// //img.dlsite.jp/...のようにschemeが無い。
+		if strings.HasPrefix(src, "//") {
+			w.SampleImages[i] = "https:" + src
+		}
+	}
+
+	// セール中は定価に取り消し線がついて別に表示される。
+	if regular := parsePrice(raw.RegularPrice); regular > 0 {
+		w.Price = regular
+		w.SalePrice = parsePrice(raw.Price)
+	} else {
+		w.Price = parsePrice(raw.Price)
+	}
+
+	if date, ok := raw.Outline["販売日"]; ok {
+		w.ReleaseDate, _ = parseDate(date)
+	}
+	return w
+}
+
+// "1,320円"のような価格を数値にする。読めなければ0。
+// "1,320円 (20%OFF)"のように後ろに割引率などがあっても、最初の数値だけを読む。
+func parsePrice(v string) int {
+	num, err := strconv.Atoi(strings.ReplaceAll(pricePattern.FindString(v), ",", ""))
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+// "2023年07月01日 0時", "2023/07/01 12:34"のような日付を読む。時刻は読まない。
+func parseDate(v string) (time.Time, error) {
+	m := datePattern.FindStringSubmatch(v)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("日付として解釈できませんでした。: %s", v)
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, jst), nil
+}
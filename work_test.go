@@ -0,0 +1,125 @@
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ブラウザで取得した値がWorkに変換されるか確認。
+func TestRawWork(t *testing.T) {
+	tests := []struct {
+		name string
+		args rawWork
+		want Work
+	}{
+		{
+			name: "regular",
+			args: rawWork{
+				Title: "作品名",
+				Maker: "サークル名",
+				Price: "1,320円",
+				Outline: map[string]string{
+					"販売日":    "2023年07月01日 0時",
+					"年齢指定":   "18禁",
+					"ファイル形式": "MP3 / WAV",
+					"ファイル容量": "1.2GB",
+				},
+				Genres:       []string{"ASMR", "バイノーラル"},
+				SampleImages: []string{"//img.dlsite.jp/modpub/images2/work/doujin/RJ01000000_img_main.jpg"},
+			},
+			want: Work{
+				Title:        "作品名",
+				Maker:        "サークル名",
+				Price:        1320,
+				ReleaseDate:  time.Date(2023, 7, 1, 0, 0, 0, 0, jst),
+				Genres:       []string{"ASMR", "バイノーラル"},
+				AgeRating:    "18禁",
+				FileFormat:   "MP3 / WAV",
+				FileSize:     "1.2GB",
+				SampleImages: []string{"https://img.dlsite.jp/modpub/images2/work/doujin/RJ01000000_img_main.jpg"},
+			},
+		},
+		{
+			name: "sale",
+			args: rawWork{
+				Title:        "作品名",
+				Price:        "660円",
+				RegularPrice: "1,320円",
+			},
+			want: Work{
+				Title:     "作品名",
+				Price:     1320,
+				SalePrice: 660,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.args.work()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rawWork.work() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want int
+	}{
+		{name: "yen", args: "1,320円", want: 1320},
+		{name: "point", args: "120pt", want: 120},
+		{name: "point with rate", args: "66pt(5%還元)", want: 66},
+		{name: "yen with discount", args: "1,320円 (20%OFF)", want: 1320},
+		{name: "large", args: "12,345,678円", want: 12345678},
+		{name: "empty", args: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePrice(tt.args); got != tt.want {
+				t.Errorf("parsePrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 作品IDを入れる%sが無いWorkUrlをエラーにすることの確認。
+func TestWorkURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		workUrl string
+		want    string
+		wantErr bool
+	}{
+		{name: "ok", workUrl: "https://www.dlsite.com/maniax/work/=/product_id/%s.html", want: "https://www.dlsite.com/maniax/work/=/product_id/RJ123456.html"},
+		{name: "empty", workUrl: "", wantErr: true},
+		{name: "no verb", workUrl: "https://www.dlsite.com/maniax/work/", wantErr: true},
+		{name: "two verbs", workUrl: "https://www.dlsite.com/%s/work/%s.html", wantErr: true},
+		{name: "other verb", workUrl: "https://www.dlsite.com/%d/work/%s.html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ScrapingTaskManager{WorkUrl: tt.workUrl}
+			got, err := s.workURL("RJ123456")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("workURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("workURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// WorkUrlを設定していないと、ページに移動する前にエラーになることの確認。
+func TestScrapeWorkTasksUrlNotSet(t *testing.T) {
+	err := ScrapingTaskManager{}.ScrapeWorkTasks("RJ123456", &Work{}).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "WorkUrl") {
+		t.Errorf("ScrapeWorkTasks() error = %v, want contains %v", err, "WorkUrl")
+	}
+}